@@ -0,0 +1,145 @@
+package analyzer
+
+import (
+	"math"
+	"sort"
+)
+
+// worstOffenders is the number of functions listed in Summary.WorstOffenders.
+const worstOffenders = 5
+
+// Summary aggregates the metrics of the functions in a file or package.
+type Summary struct {
+	Name                   string           `json:"name"`
+	Functions              int              `json:"functions"`              // Number of functions, methods and closures.
+	LinesOfCode            int              `json:"linesOfCode"`            // Lines of code of the top-level functions.
+	Cyclomatic             MetricSummary    `json:"cyclomatic"`             // Cyclomatic complexity statistics.
+	Cognitive              MetricSummary    `json:"cognitive"`              // Cognitive complexity statistics.
+	HalsteadEffort         float64          `json:"halsteadEffort"`         // Total Halstead effort.
+	AverageMaintainability float64          `json:"averageMaintainability"` // Mean maintainability index.
+	WorstOffenders         []*MetricsResult `json:"worstOffenders"`         // Most complex functions, worst first.
+}
+
+// MetricSummary holds the distribution of an integer metric.
+type MetricSummary struct {
+	Total   int     `json:"total"`
+	Average float64 `json:"average"`
+	Max     int     `json:"max"`
+	Median  float64 `json:"median"`
+	P90     float64 `json:"p90"`
+	P95     float64 `json:"p95"`
+}
+
+// Summarize aggregates results under the given name. Closures folded into
+// their parent are counted once, in the parent's complexity, lines and
+// effort.
+func Summarize(name string, results []*MetricsResult) *Summary {
+	summary := &Summary{
+		Name:           name,
+		Functions:      len(results),
+		WorstOffenders: []*MetricsResult{},
+	}
+	if len(results) == 0 {
+		return summary
+	}
+
+	cyclomatic := make([]int, 0, len(results))
+	cognitive := make([]int, 0, len(results))
+	maintainability := 0.0
+	for _, result := range results {
+		maintainability += result.MaintainabilityIndex
+		// Closures lie within their parent's lines.
+		if result.Kind != KindClosure {
+			summary.LinesOfCode += result.LinesOfCode
+		}
+		if result.Folded {
+			continue
+		}
+		cyclomatic = append(cyclomatic, result.CyclomaticComplexity)
+		cognitive = append(cognitive, result.CognitiveComplexity)
+		summary.HalsteadEffort += result.HalsteadEffort
+	}
+	summary.Cyclomatic = summarizeMetric(cyclomatic)
+	summary.Cognitive = summarizeMetric(cognitive)
+	summary.HalsteadEffort = math.Round(summary.HalsteadEffort*100) / 100
+	summary.AverageMaintainability = math.Round(maintainability/float64(len(results))*100) / 100
+
+	worst := append([]*MetricsResult(nil), results...)
+	sort.SliceStable(worst, func(i, j int) bool {
+		if worst[i].CognitiveComplexity != worst[j].CognitiveComplexity {
+			return worst[i].CognitiveComplexity > worst[j].CognitiveComplexity
+		}
+		return worst[i].CyclomaticComplexity > worst[j].CyclomaticComplexity
+	})
+	if len(worst) > worstOffenders {
+		worst = worst[:worstOffenders]
+	}
+	summary.WorstOffenders = worst
+
+	return summary
+}
+
+// SummarizeFiles aggregates results per file, sorted by file name.
+func SummarizeFiles(results []*MetricsResult) []*Summary {
+	return summarizeBy(results, func(r *MetricsResult) string { return r.File })
+}
+
+// SummarizePackages aggregates results per package, sorted by import path.
+func SummarizePackages(results []*MetricsResult) []*Summary {
+	return summarizeBy(results, func(r *MetricsResult) string { return r.Package })
+}
+
+// SummarizeClasses aggregates results per file class, sorted by class.
+func SummarizeClasses(results []*MetricsResult) []*Summary {
+	return summarizeBy(results, func(r *MetricsResult) string { return r.Classification })
+}
+
+// summarizeBy groups results by key and summarizes each group.
+func summarizeBy(results []*MetricsResult, key func(*MetricsResult) string) []*Summary {
+	groups := make(map[string][]*MetricsResult)
+	var names []string
+	for _, result := range results {
+		name := key(result)
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], result)
+	}
+	sort.Strings(names)
+
+	summaries := make([]*Summary, 0, len(names))
+	for _, name := range names {
+		summaries = append(summaries, Summarize(name, groups[name]))
+	}
+	return summaries
+}
+
+// summarizeMetric computes the distribution of values.
+func summarizeMetric(values []int) MetricSummary {
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+
+	var summary MetricSummary
+	if len(sorted) == 0 {
+		return summary
+	}
+	for _, v := range sorted {
+		summary.Total += v
+	}
+	summary.Average = math.Round(float64(summary.Total)/float64(len(sorted))*100) / 100
+	summary.Max = sorted[len(sorted)-1]
+	summary.Median = percentile(sorted, 50)
+	summary.P90 = percentile(sorted, 90)
+	summary.P95 = percentile(sorted, 95)
+	return summary
+}
+
+// percentile returns the p-th percentile of sorted values using the
+// nearest-rank method.
+func percentile(sorted []int, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return float64(sorted[rank-1])
+}
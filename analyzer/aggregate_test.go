@@ -0,0 +1,59 @@
+package analyzer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSummarizeFoldedClosures(t *testing.T) {
+	src := `package example
+
+func Parent(a bool) {
+	if a {
+	}
+	_ = func(b bool) {
+		if b {
+		}
+	}
+}
+
+var initializer = func(c bool) {
+	if c {
+	}
+}
+`
+	tests := []struct {
+		fold       bool
+		cyclomatic int
+		cognitive  int
+		functions  int
+	}{
+		// Parent 2 and 1, its closure 2 and 1, the initializer 2 and 1
+		{false, 6, 3, 3},
+		// Parent 3 and 3 with its closure folded in, the initializer 2 and 1
+		{true, 5, 4, 3},
+	}
+	for _, tt := range tests {
+		fa, err := NewFileAnalyzer("example.go", []byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fa.SetFoldClosures(tt.fold)
+		results := fa.AnalyzeFile()
+		summary := Summarize("example", results)
+
+		effort := 0.0
+		for _, result := range results {
+			if result.Name == "Parent" || result.Name == "init.func1" {
+				effort += result.HalsteadEffort
+			}
+		}
+		if effort = math.Round(effort*100) / 100; tt.fold && summary.HalsteadEffort != effort {
+			t.Errorf("fold: Halstead effort = %v, want Parent's and the initializer's, %v", summary.HalsteadEffort, effort)
+		}
+		if summary.Cyclomatic.Total != tt.cyclomatic || summary.Cognitive.Total != tt.cognitive || summary.Functions != tt.functions {
+			t.Errorf("fold %v: cyclomatic, cognitive, functions = %d, %d, %d, want %d, %d, %d", tt.fold,
+				summary.Cyclomatic.Total, summary.Cognitive.Total, summary.Functions, tt.cyclomatic, tt.cognitive, tt.functions)
+		}
+	}
+}
@@ -1,29 +1,61 @@
 package analyzer
 
 import (
+	"context"
+	"fmt"
 	"go/ast"
-	"go/parser"
 	"go/token"
+	"go/types"
 	"math"
+	"strings"
 )
 
 // FileAnalyzer represents a file analyzer.
 type FileAnalyzer struct {
-	fset *token.FileSet
-	ast  *ast.File
+	fset         *token.FileSet
+	ast          *ast.File
+	pkgPath      string
+	foldClosures bool
+	metrics      map[string]bool
+	class        string
+	buildable    bool        // Whether the file matches the default build context.
+	initBase     int         // init functions in the files before, see NumberInits.
+	initLitBase  int         // Initializer function literals in the files before.
+	info         *types.Info // Set when the file is type-checked, see LoadPackages.
 }
 
 func NewFileAnalyzer(filename string, content []byte) (*FileAnalyzer, error) {
-	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, filename, content, parser.ParseComments)
-	if err != nil {
-		return nil, err
+	return ParseFile(token.NewFileSet(), filename, content)
+}
+
+// Filename returns the name the file was parsed under.
+func (fa *FileAnalyzer) Filename() string {
+	return fa.fset.Position(fa.ast.Pos()).Filename
+}
+
+// SetFoldClosures controls whether the bodies of function literals count
+// towards the metrics of the enclosing function. Closures are always reported
+// as units of their own as well.
+func (fa *FileAnalyzer) SetFoldClosures(fold bool) {
+	fa.foldClosures = fold
+}
+
+// SetMetrics restricts the metrics computed to the named ones; see Metrics.
+// Metrics left out are reported as zero. An empty list selects every metric.
+func (fa *FileAnalyzer) SetMetrics(metrics []string) {
+	fa.metrics = nil
+	if len(metrics) == 0 {
+		return
 	}
+	fa.metrics = make(map[string]bool, len(metrics))
+	for _, metric := range metrics {
+		fa.metrics[metric] = true
+	}
+}
 
-	return &FileAnalyzer{
-		fset: fset,
-		ast:  node,
-	}, nil
+// computes reports whether the named metric is selected.
+func (fa *FileAnalyzer) computes(metric string) bool {
+	return fa.metrics == nil || fa.metrics[metric]
 }
 
 // AnalyzeFunction analyzes a function declaration and returns the metrics.
@@ -32,20 +64,109 @@ func (fa *FileAnalyzer) AnalyzeFunction(funcDecl *ast.FuncDecl) *MetricsResult {
 		return nil
 	}
 
-	cyclomaticComplexity := fa.CalculateCyclomaticComplexity(funcDecl)
-	cognitiveComplexity := fa.CalculateCognitiveComplexity(funcDecl)
-	linesOfCode := fa.CountLinesOfCode(funcDecl)
-	volume, difficulty, effort := fa.calculateHalsteadMetrics(funcDecl)
-	maintainabilityIndex := fa.CalculateMaintainabilityIndex(cyclomaticComplexity, volume, linesOfCode)
-	nestedDepth := fa.calculateNestedDepth(funcDecl)
-	commentDensity := fa.calculateCommentDensity(funcDecl)
-	paramCount := 0
-	if funcDecl.Type.Params != nil {
-		for _, field := range funcDecl.Type.Params.List {
+	id := fa.functionIdentity(funcDecl)
+	result := fa.analyzeUnit(funcDecl, funcDecl.Type)
+	result.Fingerprint = fa.fingerprint(funcDecl.Type, funcDecl.Body)
+	result.Name = funcDecl.Name.Name
+	result.QualifiedName = id.qualified
+	result.Kind = id.kind
+	result.Receiver = id.receiver
+	result.Suppressions = append(fa.fileSuppressions(), fa.suppressions(funcDecl.Doc)...)
+	if fa.info != nil {
+		result.object, _ = fa.info.Defs[funcDecl.Name].(*types.Func)
+	}
+	return result
+}
+
+// analyzeFuncLit analyzes a function literal nested in the unit named parent.
+// name is the synthetic name of the literal, e.g. Parent.func1.
+func (fa *FileAnalyzer) analyzeFuncLit(funcLit *ast.FuncLit, name, parent string) *MetricsResult {
+	result := fa.analyzeUnit(funcLit, funcLit.Type)
+	result.Fingerprint = fa.fingerprint(funcLit.Type, funcLit.Body)
+	result.Name = name
+	result.QualifiedName = name
+	if pkgPath := fa.PackagePath(); pkgPath != "" {
+		result.QualifiedName = pkgPath + "." + name
+	}
+	result.Kind = KindClosure
+	result.Parent = parent
+	result.Folded = fa.foldClosures
+	return result
+}
+
+// analyzeUnit computes the metrics of a function declaration or literal.
+func (fa *FileAnalyzer) analyzeUnit(node ast.Node, funcType *ast.FuncType) *MetricsResult {
+	// The maintainability index is derived from cyclomatic complexity,
+	// Halstead volume and lines of code, which it needs even when they are
+	// not reported. So does the information flow, computed by LinkCalls.
+	maintainability := fa.computes(MetricMaintainability)
+	callMetrics := fa.computes(MetricCalls) && fa.info != nil
+
+	var (
+		cyclomaticComplexity, cognitiveComplexity, linesOfCode int
+		nestedDepth, paramCount, returnCount, interfaceCalls   int
+		npathComplexity                                        int64
+		calls                                                  []call
+		cognitiveIncrements                                    []CognitiveIncrement
+		volume, difficulty, effort                             float64
+		maintainabilityIndex, commentDensity                   float64
+	)
+	if fa.computes(MetricCyclomatic) || maintainability {
+		cyclomaticComplexity = fa.CalculateCyclomaticComplexity(node)
+	}
+	if fa.computes(MetricNPath) {
+		npathComplexity = fa.CalculateNPathComplexity(node)
+	}
+	if fa.computes(MetricCognitive) {
+		cognitiveIncrements = fa.ExplainCognitiveComplexity(node)
+		for _, increment := range cognitiveIncrements {
+			cognitiveComplexity += increment.Base + increment.Nesting
+		}
+	}
+	if fa.computes(MetricLines) || maintainability || callMetrics {
+		linesOfCode = fa.CountLinesOfCode(node)
+	}
+	if fa.computes(MetricHalstead) || maintainability {
+		volume, difficulty, effort = fa.calculateHalsteadMetrics(node)
+	}
+	if maintainability {
+		maintainabilityIndex = fa.CalculateMaintainabilityIndex(cyclomaticComplexity, volume, linesOfCode)
+	}
+	if fa.computes(MetricNesting) {
+		nestedDepth = fa.calculateNestedDepth(node)
+	}
+	if fa.computes(MetricComments) {
+		commentDensity = fa.calculateCommentDensity(node)
+	}
+	if fa.computes(MetricParameters) && funcType.Params != nil {
+		for _, field := range funcType.Params.List {
 			paramCount += len(field.Names)
 		}
 	}
-	returnCount := fa.countReturnStatements(funcDecl)
+	if fa.computes(MetricReturns) {
+		returnCount = fa.countReturnStatements(node)
+	}
+	if callMetrics {
+		calls = fa.calls(node)
+		for _, c := range calls {
+			if c.dynamic {
+				interfaceCalls++
+			}
+		}
+	}
+
+	// Drop what was only computed for the maintainability index or the
+	// information flow
+	lines := linesOfCode
+	if !fa.computes(MetricCyclomatic) {
+		cyclomaticComplexity = 0
+	}
+	if !fa.computes(MetricLines) {
+		linesOfCode = 0
+	}
+	if !fa.computes(MetricHalstead) {
+		volume, difficulty, effort = 0, 0, 0
+	}
 
 	if math.IsNaN(volume) || math.IsInf(volume, 0) {
 		volume = 0
@@ -63,10 +184,23 @@ func (fa *FileAnalyzer) AnalyzeFunction(funcDecl *ast.FuncDecl) *MetricsResult {
 		commentDensity = 0
 	}
 
+	pos := fa.fset.Position(node.Pos())
+	end := fa.fset.Position(node.End())
+
 	return &MetricsResult{
-		Name:                 funcDecl.Name.Name,
+		Package:              fa.PackagePath(),
+		Classification:       fa.class,
+		File:                 pos.Filename,
+		Line:                 pos.Line,
+		Column:               pos.Column,
+		EndLine:              end.Line,
+		EndColumn:            end.Column,
+		Offset:               pos.Offset,
+		EndOffset:            end.Offset,
 		CyclomaticComplexity: cyclomaticComplexity,
+		NPathComplexity:      npathComplexity,
 		CognitiveComplexity:  cognitiveComplexity,
+		CognitiveIncrements:  cognitiveIncrements,
 		LinesOfCode:          linesOfCode,
 		HalsteadVolume:       math.Round(volume*100) / 100,
 		HalsteadDifficulty:   math.Round(difficulty*100) / 100,
@@ -76,22 +210,112 @@ func (fa *FileAnalyzer) AnalyzeFunction(funcDecl *ast.FuncDecl) *MetricsResult {
 		CommentDensity:       math.Round(commentDensity*100) / 100,
 		FunctionParameters:   paramCount,
 		ReturnStatements:     returnCount,
+		InterfaceCalls:       interfaceCalls,
+		calls:                calls,
+		lines:                lines,
 	}
 }
 
-// AnalyzeFile analyzes a file and returns the metrics for each function.
+// AnalyzeFile analyzes a file and returns the metrics for each function,
+// followed by the function literals it contains.
 func (fa *FileAnalyzer) AnalyzeFile() []*MetricsResult {
+	results, _ := fa.AnalyzeFileContext(context.Background())
+	return results
+}
+
+// AnalyzeFileContext is like AnalyzeFile but stops before the next function
+// when ctx is done, returning the results so far with the context's error.
+func (fa *FileAnalyzer) AnalyzeFileContext(ctx context.Context) ([]*MetricsResult, error) {
 	var results []*MetricsResult
+	var initLits []*ast.FuncLit
 
-	ast.Inspect(fa.ast, func(n ast.Node) bool {
-		if funcDecl, ok := n.(*ast.FuncDecl); ok {
-			result := fa.AnalyzeFunction(funcDecl)
-			if result != nil {
-				results = append(results, result)
+	for _, decl := range fa.ast.Decls {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			result := fa.AnalyzeFunction(decl)
+			if result == nil {
+				continue
+			}
+			results = append(results, result)
+			if decl.Body != nil {
+				local := strings.TrimPrefix(result.QualifiedName, fa.PackagePath()+".")
+				// Closures share the directives of their function
+				for _, closure := range fa.analyzeFuncLits(decl.Body, local+".func", result.QualifiedName) {
+					closure.Suppressions = result.Suppressions
+					results = append(results, closure)
+				}
 			}
+		case *ast.GenDecl:
+			initLits = append(initLits, childFuncLits(decl)...)
+		}
+	}
+
+	// Closures in package-level initializers belong to the package's init,
+	// matching the names the compiler gives them.
+	parent := "init"
+	if pkgPath := fa.PackagePath(); pkgPath != "" {
+		parent = pkgPath + "." + parent
+	}
+	fileSuppressions := fa.fileSuppressions()
+	for i, lit := range initLits {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		closures := fa.analyzeFuncLitTree(lit, fmt.Sprintf("init.func%d", fa.initLitBase+i+1), parent)
+		// The init function they belong to is not analyzed
+		closures[0].Folded = false
+		for _, closure := range closures {
+			closure.Suppressions = fileSuppressions
+			results = append(results, closure)
+		}
+	}
+
+	return results, nil
+}
+
+// analyzeFuncLits analyzes the function literals directly nested in node and,
+// recursively, the literals nested in those. prefix is the name of the
+// enclosing unit with its numbering prefix, e.g. "Parent.func".
+func (fa *FileAnalyzer) analyzeFuncLits(node ast.Node, prefix, parent string) []*MetricsResult {
+	var results []*MetricsResult
+	for i, lit := range childFuncLits(node) {
+		results = append(results, fa.analyzeFuncLitTree(lit, fmt.Sprintf("%s%d", prefix, i+1), parent)...)
+	}
+	return results
+}
+
+// analyzeFuncLitTree analyzes a function literal followed by its nested
+// literals, which are numbered Parent.func1.1, Parent.func1.2 and so on.
+func (fa *FileAnalyzer) analyzeFuncLitTree(lit *ast.FuncLit, name, parent string) []*MetricsResult {
+	result := fa.analyzeFuncLit(lit, name, parent)
+	return append([]*MetricsResult{result}, fa.analyzeFuncLits(lit.Body, name+".", result.QualifiedName)...)
+}
+
+// childFuncLits returns the function literals in node that are not nested in
+// another function literal, in source order.
+func childFuncLits(node ast.Node) []*ast.FuncLit {
+	var lits []*ast.FuncLit
+	ast.Inspect(node, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.FuncLit); ok && n != node {
+			lits = append(lits, lit)
+			return false
 		}
 		return true
 	})
+	return lits
+}
 
-	return results
+// visitor wraps an ast.Inspect callback for the unit rooted at root. Unless
+// closures are folded into their parent, the walk does not descend into
+// function literals nested below root.
+func (fa *FileAnalyzer) visitor(root ast.Node, f func(ast.Node) bool) func(ast.Node) bool {
+	return func(n ast.Node) bool {
+		if _, ok := n.(*ast.FuncLit); ok && n != root && !fa.foldClosures {
+			return false
+		}
+		return f(n)
+	}
 }
@@ -0,0 +1,19 @@
+package analyzer
+
+import "testing"
+
+// analyzeSource analyzes the file src, in package example, and returns its
+// results by name.
+func analyzeSource(t *testing.T, src string) map[string]*MetricsResult {
+	t.Helper()
+	fa, err := NewFileAnalyzer("example.go", []byte(src))
+	if err != nil {
+		t.Fatalf("parsing source: %v", err)
+	}
+	fa.SetPackagePath("example")
+	results := make(map[string]*MetricsResult)
+	for _, result := range fa.AnalyzeFile() {
+		results[result.Name] = result
+	}
+	return results
+}
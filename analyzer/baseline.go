@@ -0,0 +1,181 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+)
+
+// Baseline records accepted violations so that only new ones fail a check.
+// Entries are keyed by qualified function name and metric, which survive
+// edits that move a function within its file.
+type Baseline struct {
+	Entries []BaselineEntry `json:"entries"`
+}
+
+// BaselineEntry is an accepted violation and the value it was accepted at.
+type BaselineEntry struct {
+	Function string  `json:"function"` // Qualified name.
+	Metric   string  `json:"metric"`
+	Value    float64 `json:"value"`
+}
+
+// BaselineCheck sorts violations by how they relate to a baseline.
+type BaselineCheck struct {
+	New       []Violation        `json:"new"`       // Violations missing from the baseline.
+	Worse     []Violation        `json:"worse"`     // Violations worse than their baseline entry.
+	Unchanged []Violation        `json:"unchanged"` // Violations at or better than their entry.
+	Improved  []BaselineImproved `json:"improved"`  // Entries whose violation improved or disappeared.
+}
+
+// BaselineImproved pairs a baseline entry with its current value. Fixed is
+// set when the function was analyzed and no longer violates the limit.
+type BaselineImproved struct {
+	BaselineEntry
+	Current float64 `json:"current"`
+	Fixed   bool    `json:"fixed"`
+}
+
+type baselineKey struct {
+	function string
+	metric   string
+}
+
+// NewBaseline returns a baseline accepting violations.
+func NewBaseline(violations []Violation) *Baseline {
+	entries := make(map[baselineKey]BaselineEntry)
+	for _, v := range violations {
+		key := baselineKey{v.QualifiedName, v.Metric}
+		if entry, ok := entries[key]; ok && !worse(v.Metric, v.Value, entry.Value) {
+			continue
+		}
+		entries[key] = BaselineEntry{Function: v.QualifiedName, Metric: v.Metric, Value: v.Value}
+	}
+
+	baseline := &Baseline{Entries: make([]BaselineEntry, 0, len(entries))}
+	for _, entry := range entries {
+		baseline.Entries = append(baseline.Entries, entry)
+	}
+	baseline.sort()
+	return baseline
+}
+
+// ReadBaseline reads a baseline written by WriteFile.
+func ReadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, err
+	}
+	return &baseline, nil
+}
+
+// WriteFile writes the baseline as indented JSON, sorted so that it diffs
+// well under version control.
+func (b *Baseline) WriteFile(path string) error {
+	b.sort()
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Check compares the violations of results with the baseline. Entries of
+// functions missing from results are left alone: they may just not have
+// been analyzed, and only a fresh baseline forgets removed functions.
+func (b *Baseline) Check(results []*MetricsResult, violations []Violation) BaselineCheck {
+	accepted := b.index()
+	current := make(map[baselineKey]float64)
+	analyzed := make(map[string]bool, len(results))
+	for _, result := range results {
+		analyzed[result.QualifiedName] = true
+	}
+
+	var check BaselineCheck
+	for _, v := range violations {
+		key := baselineKey{v.QualifiedName, v.Metric}
+		if value, ok := current[key]; !ok || worse(v.Metric, v.Value, value) {
+			current[key] = v.Value
+		}
+
+		entry, ok := accepted[key]
+		switch {
+		case !ok:
+			check.New = append(check.New, v)
+		case worse(v.Metric, v.Value, entry.Value):
+			check.Worse = append(check.Worse, v)
+		default:
+			check.Unchanged = append(check.Unchanged, v)
+		}
+	}
+
+	for _, entry := range b.Entries {
+		value, ok := current[baselineKey{entry.Function, entry.Metric}]
+		switch {
+		case !ok && !analyzed[entry.Function]:
+			// Not analyzed in this run
+		case !ok:
+			check.Improved = append(check.Improved, BaselineImproved{BaselineEntry: entry, Fixed: true})
+		case worse(entry.Metric, entry.Value, value):
+			check.Improved = append(check.Improved, BaselineImproved{BaselineEntry: entry, Current: value})
+		}
+	}
+	return check
+}
+
+// Failed reports whether the check found new or worse violations.
+func (c BaselineCheck) Failed() bool {
+	return len(c.New) > 0 || len(c.Worse) > 0
+}
+
+// Tighten returns the baseline lowered to the current violations: fixed
+// entries are dropped and improved ones take their current value. New and
+// worse violations are not accepted.
+func (b *Baseline) Tighten(check BaselineCheck) *Baseline {
+	improved := make(map[baselineKey]BaselineImproved)
+	for _, entry := range check.Improved {
+		improved[baselineKey{entry.Function, entry.Metric}] = entry
+	}
+
+	tightened := &Baseline{Entries: []BaselineEntry{}}
+	for _, entry := range b.Entries {
+		if i, ok := improved[baselineKey{entry.Function, entry.Metric}]; ok {
+			if i.Fixed {
+				continue
+			}
+			entry.Value = i.Current
+		}
+		tightened.Entries = append(tightened.Entries, entry)
+	}
+	return tightened
+}
+
+func (b *Baseline) index() map[baselineKey]BaselineEntry {
+	index := make(map[baselineKey]BaselineEntry, len(b.Entries))
+	for _, entry := range b.Entries {
+		index[baselineKey{entry.Function, entry.Metric}] = entry
+	}
+	return index
+}
+
+func (b *Baseline) sort() {
+	sort.Slice(b.Entries, func(i, j int) bool {
+		if b.Entries[i].Function != b.Entries[j].Function {
+			return b.Entries[i].Function < b.Entries[j].Function
+		}
+		return b.Entries[i].Metric < b.Entries[j].Metric
+	})
+}
+
+// worse reports whether value a of metric is worse than b. Maintainability
+// is a lower bound; every other metric is an upper bound.
+func worse(metric string, a, b float64) bool {
+	if metric == MetricMaintainability {
+		return a < b
+	}
+	return a > b
+}
@@ -0,0 +1,89 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBaselineCheck(t *testing.T) {
+	baseline := NewBaseline([]Violation{
+		{QualifiedName: "example.Worse", Metric: MetricCyclomatic, Value: 12},
+		{QualifiedName: "example.Same", Metric: MetricCyclomatic, Value: 12},
+		{QualifiedName: "example.Better", Metric: MetricCyclomatic, Value: 12},
+		{QualifiedName: "example.Fixed", Metric: MetricCyclomatic, Value: 12},
+		{QualifiedName: "example.Missing", Metric: MetricCyclomatic, Value: 12},
+		{QualifiedName: "example.Lower", Metric: MetricMaintainability, Value: 10},
+	})
+	var results []*MetricsResult
+	for _, name := range []string{"example.Worse", "example.Same", "example.Better", "example.Fixed", "example.New", "example.Lower"} {
+		results = append(results, &MetricsResult{QualifiedName: name})
+	}
+	violations := []Violation{
+		{QualifiedName: "example.Worse", Metric: MetricCyclomatic, Value: 13},
+		{QualifiedName: "example.Same", Metric: MetricCyclomatic, Value: 12},
+		{QualifiedName: "example.Better", Metric: MetricCyclomatic, Value: 11},
+		{QualifiedName: "example.New", Metric: MetricCyclomatic, Value: 11},
+		{QualifiedName: "example.Lower", Metric: MetricMaintainability, Value: 15},
+	}
+
+	check := baseline.Check(results, violations)
+	names := func(violations []Violation) []string {
+		var names []string
+		for _, v := range violations {
+			names = append(names, v.QualifiedName)
+		}
+		return names
+	}
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"new", names(check.New), []string{"example.New"}},
+		{"worse", names(check.Worse), []string{"example.Worse"}},
+		{"unchanged", names(check.Unchanged), []string{"example.Same", "example.Better", "example.Lower"}},
+		{"improved", check.Improved, []BaselineImproved{
+			{BaselineEntry: BaselineEntry{"example.Better", MetricCyclomatic, 12}, Current: 11},
+			{BaselineEntry: BaselineEntry{"example.Fixed", MetricCyclomatic, 12}, Fixed: true},
+			{BaselineEntry: BaselineEntry{"example.Lower", MetricMaintainability, 10}, Current: 15},
+		}},
+		{"failed", check.Failed(), true},
+		{"tightened", baseline.Tighten(check).Entries, []BaselineEntry{
+			{"example.Better", MetricCyclomatic, 11},
+			{"example.Lower", MetricMaintainability, 15},
+			{"example.Missing", MetricCyclomatic, 12},
+			{"example.Same", MetricCyclomatic, 12},
+			{"example.Worse", MetricCyclomatic, 12},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("got %+v, want %+v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBaselineKeepsWorstValue(t *testing.T) {
+	tests := []struct {
+		metric string
+		values []float64
+		want   float64
+	}{
+		{MetricCyclomatic, []float64{12, 15, 11}, 15},
+		{MetricMaintainability, []float64{12, 8, 11}, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.metric, func(t *testing.T) {
+			var violations []Violation
+			for _, value := range tt.values {
+				violations = append(violations, Violation{QualifiedName: "example.f", Metric: tt.metric, Value: value})
+			}
+			want := []BaselineEntry{{"example.f", tt.metric, tt.want}}
+			if got := NewBaseline(violations).Entries; !reflect.DeepEqual(got, want) {
+				t.Errorf("entries = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
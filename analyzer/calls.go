@@ -0,0 +1,191 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+)
+
+// call is a call site resolved to the function or method it invokes.
+type call struct {
+	callee  *types.Func
+	dynamic bool // Dispatched through an interface.
+}
+
+// CallGraph is the static call graph of a set of analyzed functions. Calls
+// through function values are not resolved; calls through interfaces lead
+// to the interface method.
+type CallGraph struct {
+	Nodes []*CallNode `json:"nodes"` // Sorted by name.
+	Edges []*CallEdge `json:"edges"` // Sorted by caller, then callee.
+}
+
+// CallNode is a function of the call graph.
+type CallNode struct {
+	Name     string         `json:"name"`               // Qualified name, as in MetricsResult.QualifiedName.
+	Package  string         `json:"package"`            // Import path of the function's package.
+	External bool           `json:"external,omitempty"` // Called but not among the analyzed functions.
+	Result   *MetricsResult `json:"result,omitempty"`   // Metrics of an analyzed function.
+}
+
+// CallEdge records that a function calls another.
+type CallEdge struct {
+	Caller  string `json:"caller"`
+	Callee  string `json:"callee"`
+	Calls   int    `json:"calls"`             // Number of call sites.
+	Dynamic bool   `json:"dynamic,omitempty"` // Whether the calls go through an interface.
+}
+
+// LinkCalls builds the call graph of results, which must come from
+// type-checked files (see LoadPackages), and records the fan-in, fan-out
+// and information flow of every result. Only results analyzed together are
+// linked, so the fan-in of a function counts its callers among results.
+func LinkCalls(results []*MetricsResult) *CallGraph {
+	names := make(map[*types.Func]string)
+	nodes := make(map[string]*CallNode)
+	for _, result := range results {
+		if result.object != nil {
+			names[result.object] = result.QualifiedName
+		}
+		nodes[result.QualifiedName] = &CallNode{Name: result.QualifiedName, Package: result.Package, Result: result}
+	}
+
+	edges := make(map[[2]string]*CallEdge)
+	for _, result := range results {
+		for _, c := range result.calls {
+			callee := c.callee.Origin()
+			name, ok := names[callee]
+			if !ok {
+				name = funcName(callee)
+				if _, ok := nodes[name]; !ok {
+					node := &CallNode{Name: name, External: true}
+					if callee.Pkg() != nil {
+						node.Package = callee.Pkg().Path()
+					}
+					nodes[name] = node
+				}
+			}
+
+			key := [2]string{result.QualifiedName, name}
+			edge, ok := edges[key]
+			if !ok {
+				edge = &CallEdge{Caller: key[0], Callee: key[1]}
+				edges[key] = edge
+			}
+			edge.Calls++
+			edge.Dynamic = edge.Dynamic || c.dynamic
+		}
+	}
+
+	graph := &CallGraph{
+		Nodes: make([]*CallNode, 0, len(nodes)),
+		Edges: make([]*CallEdge, 0, len(edges)),
+	}
+	for _, node := range nodes {
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].Name < graph.Nodes[j].Name })
+
+	// Recursive calls neither add a caller nor a callee
+	fanIn := make(map[string]int)
+	fanOut := make(map[string]int)
+	for _, edge := range edges {
+		graph.Edges = append(graph.Edges, edge)
+		if edge.Caller != edge.Callee {
+			fanOut[edge.Caller]++
+			fanIn[edge.Callee]++
+		}
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].Caller != graph.Edges[j].Caller {
+			return graph.Edges[i].Caller < graph.Edges[j].Caller
+		}
+		return graph.Edges[i].Callee < graph.Edges[j].Callee
+	})
+
+	for _, result := range results {
+		if result.calls == nil && result.object == nil {
+			continue // Not type-checked
+		}
+		result.FanIn = fanIn[result.QualifiedName]
+		result.FanOut = fanOut[result.QualifiedName]
+		flow := int64(result.FanIn) * int64(result.FanOut)
+		result.InformationFlow = int64(result.lines) * flow * flow
+	}
+	return graph
+}
+
+// calls returns the calls in node that resolve to a function or method, in
+// source order.
+func (fa *FileAnalyzer) calls(node ast.Node) []call {
+	calls := []call{}
+	ast.Inspect(node, fa.visitor(node, func(n ast.Node) bool {
+		if expr, ok := n.(*ast.CallExpr); ok {
+			if fn, dynamic := fa.callee(expr); fn != nil {
+				calls = append(calls, call{fn, dynamic})
+			}
+		}
+		return true
+	}))
+	return calls
+}
+
+// callee returns the function or method call invokes, and whether the call
+// is dispatched dynamically through an interface. It returns nil for calls
+// of function values, conversions and builtins, and without type
+// information.
+func (fa *FileAnalyzer) callee(call *ast.CallExpr) (fn *types.Func, dynamic bool) {
+	if fa.info == nil {
+		return nil, false
+	}
+
+	fun := call.Fun
+	for {
+		switch f := fun.(type) {
+		case *ast.ParenExpr:
+			fun = f.X
+			continue
+		case *ast.IndexExpr: // Explicit instantiation of a generic function
+			fun = f.X
+			continue
+		case *ast.IndexListExpr:
+			fun = f.X
+			continue
+		}
+		break
+	}
+
+	var id *ast.Ident
+	switch f := fun.(type) {
+	case *ast.Ident:
+		id = f
+	case *ast.SelectorExpr:
+		id = f.Sel
+	default:
+		return nil, false
+	}
+	fn, ok := fa.info.Uses[id].(*types.Func)
+	if !ok {
+		return nil, false
+	}
+
+	recv := fn.Type().(*types.Signature).Recv()
+	return fn, recv != nil && types.IsInterface(recv.Type())
+}
+
+// funcName names a function that was not analyzed the way MetricsResult
+// names functions: pkg.Func, pkg.Type.Method or pkg.(*Type).Method.
+func funcName(fn *types.Func) string {
+	name := fn.Name()
+	if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+		t, pointer := recv.Type(), ""
+		if p, ok := t.(*types.Pointer); ok {
+			t, pointer = p.Elem(), "*"
+		}
+		name = qualifyMethod(pointer+types.TypeString(t, func(*types.Package) string { return "" }), name)
+	}
+	if fn.Pkg() != nil {
+		name = fn.Pkg().Path() + "." + name
+	}
+	return name
+}
@@ -0,0 +1,131 @@
+package analyzer
+
+import (
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+const callsSource = `package example
+
+type Shape interface{ Area() float64 }
+
+type Square struct{ side float64 }
+
+func (s Square) Area() float64 { return s.side * s.side }
+
+func Total(shapes []Shape) float64 {
+	total := 0.0
+	for _, s := range shapes {
+		total += s.Area()
+	}
+	return total
+}
+
+func Report() float64 {
+	return Total([]Shape{Square{2}}) + Square{1}.Area()
+}
+
+func Fact(n int) int {
+	if n == 0 {
+		return 1
+	}
+	return n * Fact(n-1)
+}
+
+func Use() {
+	_ = Report()
+	_ = Fact(3)
+	f := Fact
+	_ = f(len("x"))
+}
+`
+
+// linkSource type-checks src, in package example, and links the calls of
+// its functions.
+func linkSource(t *testing.T, src string, metrics []string) ([]*MetricsResult, *CallGraph) {
+	t.Helper()
+	fset := token.NewFileSet()
+	fa, err := ParseFile(fset, "example.go", []byte(src))
+	if err != nil {
+		t.Fatalf("parsing source: %v", err)
+	}
+	fa.SetPackagePath("example")
+	fa.SetMetrics(metrics)
+	for _, pkg := range LoadPackages(fset, []*FileAnalyzer{fa}, nil) {
+		if len(pkg.Errors) > 0 {
+			t.Fatalf("type-checking source: %v", pkg.Errors)
+		}
+	}
+	results := fa.AnalyzeFile()
+	return results, LinkCalls(results)
+}
+
+func TestLinkCallsGraph(t *testing.T) {
+	_, graph := linkSource(t, callsSource, nil)
+
+	var edges []CallEdge
+	for _, edge := range graph.Edges {
+		edges = append(edges, *edge)
+	}
+	wantEdges := []CallEdge{
+		{Caller: "example.Fact", Callee: "example.Fact", Calls: 1},
+		{Caller: "example.Report", Callee: "example.Square.Area", Calls: 1},
+		{Caller: "example.Report", Callee: "example.Total", Calls: 1},
+		{Caller: "example.Total", Callee: "example.Shape.Area", Calls: 1, Dynamic: true},
+		{Caller: "example.Use", Callee: "example.Fact", Calls: 1},
+		{Caller: "example.Use", Callee: "example.Report", Calls: 1},
+	}
+	if !reflect.DeepEqual(edges, wantEdges) {
+		t.Errorf("edges = %+v, want %+v", edges, wantEdges)
+	}
+
+	var external []string
+	for _, node := range graph.Nodes {
+		if node.External {
+			external = append(external, node.Name)
+		}
+	}
+	if want := []string{"example.Shape.Area"}; !reflect.DeepEqual(external, want) {
+		t.Errorf("external nodes = %v, want %v", external, want)
+	}
+}
+
+func TestLinkCallsFlow(t *testing.T) {
+	type flow struct {
+		FanIn, FanOut   int
+		InformationFlow int64
+		InterfaceCalls  int
+		LinesOfCode     int
+	}
+	tests := []struct {
+		name     string
+		metrics  []string
+		function string
+		want     flow
+	}{
+		{"fan-out", nil, "Report", flow{1, 2, 12, 0, 3}},
+		{"interface call", nil, "Total", flow{1, 1, 7, 1, 7}},
+		{"recursion", nil, "Fact", flow{1, 0, 0, 0, 6}},
+		{"no fan-in", nil, "Use", flow{0, 2, 0, 0, 6}},
+		{"lines not reported", []string{MetricCalls}, "Report", flow{1, 2, 12, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, _ := linkSource(t, callsSource, tt.metrics)
+			var r *MetricsResult
+			for _, result := range results {
+				if result.Name == tt.function {
+					r = result
+				}
+			}
+			if r == nil {
+				t.Fatalf("%s not analyzed", tt.function)
+			}
+			got := flow{r.FanIn, r.FanOut, r.InformationFlow, r.InterfaceCalls, r.LinesOfCode}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package analyzer
+
+import (
+	"go/ast"
+	"path/filepath"
+	"strings"
+)
+
+// File classes reported in MetricsResult.Classification.
+const (
+	ClassSource    = "source"
+	ClassTest      = "test"      // _test.go files.
+	ClassGenerated = "generated" // Files with a "// Code generated ... DO NOT EDIT." comment.
+	ClassVendor    = "vendor"    // Files below a vendor directory.
+	ClassTestdata  = "testdata"  // Files below a testdata directory.
+)
+
+// Classes lists every file class.
+var Classes = []string{ClassSource, ClassTest, ClassGenerated, ClassVendor, ClassTestdata}
+
+// ClassifyPath classifies a file by its path alone. Generated files cannot
+// be told apart by path and are classified as source or test; see
+// FileAnalyzer.Classification.
+func ClassifyPath(path string) string {
+	dirs := strings.Split(filepath.ToSlash(filepath.Dir(path)), "/")
+	for _, dir := range dirs {
+		if dir == "vendor" {
+			return ClassVendor
+		}
+	}
+	for _, dir := range dirs {
+		if dir == "testdata" {
+			return ClassTestdata
+		}
+	}
+	if strings.HasSuffix(path, "_test.go") {
+		return ClassTest
+	}
+	return ClassSource
+}
+
+// Classification returns the class of the analyzed file. Vendored and
+// testdata files keep their class even when generated.
+func (fa *FileAnalyzer) Classification() string {
+	return fa.class
+}
+
+// classify returns the class of the file parsed as fa.
+func (fa *FileAnalyzer) classify() string {
+	class := ClassifyPath(fa.Filename())
+	if (class == ClassSource || class == ClassTest) && ast.IsGenerated(fa.ast) {
+		return ClassGenerated
+	}
+	return class
+}
+
+// ContainsClass reports whether class is one of classes.
+func ContainsClass(classes []string, class string) bool {
+	for _, c := range classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
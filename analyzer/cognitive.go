@@ -0,0 +1,190 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// CognitiveIncrement explains one contribution to the cognitive complexity
+// of a function, following the SonarSource specification.
+type CognitiveIncrement struct {
+	Line      int    `json:"line"`
+	Construct string `json:"construct"` // if, else if, else, for, switch, select, &&, ||, goto, break, continue or recursion.
+	Base      int    `json:"base"`      // Structural, hybrid or fundamental increment.
+	Nesting   int    `json:"nesting"`   // Nesting bonus.
+}
+
+// ExplainCognitiveComplexity returns the increments that make up the
+// cognitive complexity of a given node, in source order.
+//
+// Control structures score 1 plus their nesting level; else if and else
+// score 1 without a nesting bonus; each sequence of like boolean operators,
+// each labeled break or continue, each goto and direct recursion score 1.
+// Function literals nest their contents when folded into their parent.
+func (fa *FileAnalyzer) ExplainCognitiveComplexity(node ast.Node) []CognitiveIncrement {
+	if node == nil {
+		return nil
+	}
+
+	var increments []CognitiveIncrement
+	add := func(pos token.Pos, construct string, nesting int) {
+		increments = append(increments, CognitiveIncrement{
+			Line:      fa.fset.Position(pos).Line,
+			Construct: construct,
+			Base:      1,
+			Nesting:   nesting,
+		})
+	}
+
+	isRecursiveCall := recursionMatcher(node)
+	recursive := false
+
+	var walk func(n ast.Node, nesting int)
+	var walkIf func(n *ast.IfStmt, nesting int)
+
+	walkIf = func(n *ast.IfStmt, nesting int) {
+		walk(n.Init, nesting)
+		walk(n.Cond, nesting)
+		walk(n.Body, nesting+1)
+		switch elseStmt := n.Else.(type) {
+		case *ast.IfStmt:
+			add(elseStmt.If, "else if", 0)
+			walkIf(elseStmt, nesting)
+		case *ast.BlockStmt:
+			add(elseStmt.Lbrace, "else", 0)
+			walk(elseStmt, nesting+1)
+		}
+	}
+
+	walk = func(n ast.Node, nesting int) {
+		if n == nil {
+			return
+		}
+		ast.Inspect(n, fa.visitor(node, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.IfStmt:
+				add(n.If, "if", nesting)
+				walkIf(n, nesting)
+				return false
+			case *ast.ForStmt:
+				add(n.For, "for", nesting)
+				walk(n.Init, nesting)
+				walk(n.Cond, nesting)
+				walk(n.Post, nesting)
+				walk(n.Body, nesting+1)
+				return false
+			case *ast.RangeStmt:
+				add(n.For, "for", nesting)
+				walk(n.X, nesting)
+				walk(n.Body, nesting+1)
+				return false
+			case *ast.SwitchStmt:
+				add(n.Switch, "switch", nesting)
+				walk(n.Init, nesting)
+				walk(n.Tag, nesting)
+				walk(n.Body, nesting+1)
+				return false
+			case *ast.TypeSwitchStmt:
+				add(n.Switch, "switch", nesting)
+				walk(n.Init, nesting)
+				walk(n.Assign, nesting)
+				walk(n.Body, nesting+1)
+				return false
+			case *ast.SelectStmt:
+				add(n.Select, "select", nesting)
+				walk(n.Body, nesting+1)
+				return false
+			case *ast.FuncLit:
+				if n == node {
+					return true
+				}
+				// Only reached when closures are folded into their parent.
+				walk(n.Body, nesting+1)
+				return false
+			case *ast.BranchStmt:
+				if n.Tok == token.GOTO || n.Label != nil {
+					add(n.TokPos, n.Tok.String(), 0)
+				}
+			case *ast.BinaryExpr:
+				if n.Op != token.LAND && n.Op != token.LOR {
+					return true
+				}
+				var ops []*ast.BinaryExpr
+				var operands []ast.Expr
+				flattenLogical(n, &ops, &operands)
+				for i, op := range ops {
+					if i == 0 || op.Op != ops[i-1].Op {
+						add(op.OpPos, op.Op.String(), 0)
+					}
+				}
+				for _, operand := range operands {
+					walk(operand, nesting)
+				}
+				return false
+			case *ast.CallExpr:
+				if !recursive && isRecursiveCall(n) {
+					recursive = true
+					add(n.Lparen, "recursion", 0)
+				}
+			}
+			return true
+		}))
+	}
+
+	switch n := node.(type) {
+	case *ast.FuncDecl:
+		walk(n.Body, 0)
+	case *ast.FuncLit:
+		walk(n.Body, 0)
+	default:
+		walk(n, 0)
+	}
+
+	return increments
+}
+
+// flattenLogical lists the && and || operators of a boolean expression in
+// source order, together with the operands between them. Parenthesized
+// expressions are operands and start sequences of their own.
+func flattenLogical(expr ast.Expr, ops *[]*ast.BinaryExpr, operands *[]ast.Expr) {
+	if b, ok := expr.(*ast.BinaryExpr); ok && (b.Op == token.LAND || b.Op == token.LOR) {
+		flattenLogical(b.X, ops, operands)
+		*ops = append(*ops, b)
+		flattenLogical(b.Y, ops, operands)
+		return
+	}
+	*operands = append(*operands, expr)
+}
+
+// recursionMatcher returns a function reporting whether a call invokes the
+// function declared by node: a plain call for functions, a call on the
+// receiver for methods. Recursion cannot be detected for function literals.
+func recursionMatcher(node ast.Node) func(*ast.CallExpr) bool {
+	funcDecl, ok := node.(*ast.FuncDecl)
+	if !ok || funcDecl.Name == nil {
+		return func(*ast.CallExpr) bool { return false }
+	}
+
+	name := funcDecl.Name.Name
+	receiver := ""
+	if funcDecl.Recv != nil && len(funcDecl.Recv.List) > 0 && len(funcDecl.Recv.List[0].Names) > 0 {
+		receiver = funcDecl.Recv.List[0].Names[0].Name
+	}
+
+	return func(call *ast.CallExpr) bool {
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			return funcDecl.Recv == nil && fun.Name == name
+		case *ast.IndexExpr: // explicit instantiation of a generic function
+			id, ok := fun.X.(*ast.Ident)
+			return ok && funcDecl.Recv == nil && id.Name == name
+		case *ast.IndexListExpr:
+			id, ok := fun.X.(*ast.Ident)
+			return ok && funcDecl.Recv == nil && id.Name == name
+		case *ast.SelectorExpr:
+			id, ok := fun.X.(*ast.Ident)
+			return ok && receiver != "" && receiver != "_" && id.Name == receiver && fun.Sel.Name == name
+		}
+		return false
+	}
+}
@@ -0,0 +1,85 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCognitiveComplexity(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string // Constructs with their base plus nesting increment.
+	}{
+		{"empty", `func f() {}`, nil},
+		{"if else if else", `func f(a, b bool) {
+	if a {
+	} else if b {
+	} else {
+	}
+}`, []string{"if+1", "else if+1", "else+1"}},
+		{"nesting", `func f(a bool, xs []int) {
+	for range xs {
+		if a {
+			switch {
+			}
+		}
+	}
+}`, []string{"for+1", "if+2", "switch+3"}},
+		{"boolean sequences", `func f(a, b, c, d bool) bool {
+	return a && b && c || d
+}`, []string{"&&+1", "||+1"}},
+		{"parenthesized", `func f(a, b, c bool) bool {
+	return a && (b && c)
+}`, []string{"&&+1", "&&+1"}},
+		{"labeled branches", `func f(xs []int) {
+outer:
+	for range xs {
+		for range xs {
+			continue outer
+		}
+		break
+	}
+	goto outer
+}`, []string{"for+1", "for+2", "continue+1", "goto+1"}},
+		{"recursion", `func f(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return f(n-1) + f(n-2)
+}`, []string{"if+1", "recursion+1"}},
+		{"method recursion", `func (t *T) f() { t.f(); f() }`, []string{"recursion+1"}},
+		{"closure not folded", `func f(a bool) {
+	_ = func() {
+		if a {
+		}
+	}
+}`, nil},
+		{"select and type switch", `func f(ch chan int, v any) {
+	select {
+	case <-ch:
+		switch v.(type) {
+		}
+	}
+}`, []string{"select+1", "switch+2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := analyzeSource(t, "package example\n\n"+tt.src+"\n")["f"]
+			var got []string
+			total := 0
+			for _, increment := range result.CognitiveIncrements {
+				score := increment.Base + increment.Nesting
+				got = append(got, fmt.Sprintf("%s+%d", increment.Construct, score))
+				total += score
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("increments = %v, want %v", got, tt.want)
+			}
+			if result.CognitiveComplexity != total {
+				t.Errorf("complexity = %d, want the sum of the increments, %d", result.CognitiveComplexity, total)
+			}
+		})
+	}
+}
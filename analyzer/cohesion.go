@@ -0,0 +1,275 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// Sizes from which a struct type is flagged as a god type: it has too many
+// methods, or too many fields used by unrelated groups of methods. A plain
+// data type with many fields is not one.
+const (
+	GodTypeMethods = 20
+	GodTypeFields  = 15
+)
+
+// TypeCohesion holds the cohesion metrics of a struct type and its methods.
+//
+// LCOM4 is the number of connected components of the graph whose nodes are
+// the methods of the type, linked when they use a common field or one calls
+// the other through the receiver. A cohesive type has one component; each
+// further component is a group of methods that could be split off.
+type TypeCohesion struct {
+	Type       string     `json:"type"` // Qualified name, e.g. pkg.Server.
+	Package    string     `json:"package"`
+	File       string     `json:"file"`
+	Line       int        `json:"line"`
+	Methods    int        `json:"methods"`    // Methods declared in the package, on the type or a pointer to it.
+	Fields     int        `json:"fields"`     // Fields, embedded ones included.
+	LCOM4      int        `json:"lcom4"`      // Connected components; 0 without methods.
+	Components [][]string `json:"components"` // Method names of every component, largest first.
+	GodType    bool       `json:"godType"`    // Too big to be cohesive; see GodTypeMethods.
+}
+
+// structFacts describes a struct type declaration.
+type structFacts struct {
+	name   string
+	fields []string
+	file   string
+	line   int
+}
+
+// methodFacts records the names a method selects on its receiver.
+type methodFacts struct {
+	recv      string // Receiver type name, without pointer or type parameters.
+	name      string
+	selectors []string
+}
+
+// Cohesion computes the cohesion metrics of the struct types declared in
+// files, grouped into packages by PackagePath.
+func Cohesion(files []*FileAnalyzer) []*TypeCohesion {
+	facts := make(map[string][]fileFacts)
+	for _, fa := range files {
+		facts[fa.PackagePath()] = append(facts[fa.PackagePath()], fa.facts())
+	}
+	metrics := []*TypeCohesion{}
+	for path, files := range facts {
+		metrics = append(metrics, cohesion(path, files)...)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Type < metrics[j].Type })
+	return metrics
+}
+
+// structs returns the struct types declared at package level.
+func (fa *FileAnalyzer) structs() []structFacts {
+	var structs []structFacts
+	for _, decl := range fa.ast.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			structType, ok := typeSpec.Type.(*ast.StructType)
+			if !ok || typeSpec.Assign.IsValid() {
+				continue
+			}
+
+			pos := fa.fset.Position(typeSpec.Pos())
+			s := structFacts{name: typeSpec.Name.Name, fields: []string{}, file: pos.Filename, line: pos.Line}
+			for _, field := range structType.Fields.List {
+				for _, name := range field.Names {
+					s.fields = append(s.fields, name.Name)
+				}
+				if len(field.Names) == 0 {
+					s.fields = append(s.fields, embeddedName(field.Type))
+				}
+			}
+			structs = append(structs, s)
+		}
+	}
+	return structs
+}
+
+// methods returns what the methods of the file select on their receiver.
+// With type information, promoted fields and methods are attributed to the
+// embedded field they come from.
+func (fa *FileAnalyzer) methods() []methodFacts {
+	var methods []methodFacts
+	for _, decl := range fa.ast.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
+			continue
+		}
+		field := funcDecl.Recv.List[0]
+		m := methodFacts{recv: embeddedName(field.Type), name: funcDecl.Name.Name}
+		if len(field.Names) == 0 || funcDecl.Body == nil {
+			methods = append(methods, m)
+			continue
+		}
+
+		recv := field.Names[0]
+		ast.Inspect(funcDecl.Body, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			if id, ok := sel.X.(*ast.Ident); !ok || id.Name != recv.Name || !fa.refersTo(id, recv) {
+				return true
+			}
+			m.selectors = append(m.selectors, sel.Sel.Name)
+			if promoted := fa.promotedThrough(sel); promoted != "" {
+				m.selectors = append(m.selectors, promoted)
+			}
+			return true
+		})
+		methods = append(methods, m)
+	}
+	return methods
+}
+
+// refersTo reports whether id denotes the receiver declared as recv. Without
+// type information every identifier of the same name does.
+func (fa *FileAnalyzer) refersTo(id, recv *ast.Ident) bool {
+	if fa.info == nil {
+		return true
+	}
+	obj := fa.info.Uses[id]
+	return obj == nil || obj == fa.info.Defs[recv]
+}
+
+// promotedThrough returns the name of the embedded field a promoted field
+// or method is selected through, or "" if sel selects a direct member or
+// there is no type information.
+func (fa *FileAnalyzer) promotedThrough(sel *ast.SelectorExpr) string {
+	if fa.info == nil {
+		return ""
+	}
+	selection, ok := fa.info.Selections[sel]
+	if !ok || len(selection.Index()) < 2 {
+		return ""
+	}
+	recv := selection.Recv()
+	if pointer, ok := recv.(*types.Pointer); ok {
+		recv = pointer.Elem()
+	}
+	structType, ok := recv.Underlying().(*types.Struct)
+	if !ok || selection.Index()[0] >= structType.NumFields() {
+		return ""
+	}
+	return structType.Field(selection.Index()[0]).Name()
+}
+
+// cohesion computes the metrics of the struct types of the package at path.
+func cohesion(path string, files []fileFacts) []*TypeCohesion {
+	methods := make(map[string][]methodFacts)
+	for _, file := range files {
+		for _, m := range file.methods {
+			methods[m.recv] = append(methods[m.recv], m)
+		}
+	}
+
+	metrics := []*TypeCohesion{}
+	for _, file := range files {
+		for _, s := range file.structs {
+			metrics = append(metrics, typeCohesion(path, s, methods[s.name]))
+		}
+	}
+	return metrics
+}
+
+// typeCohesion computes the metrics of a struct type with the given methods.
+func typeCohesion(path string, s structFacts, methods []methodFacts) *TypeCohesion {
+	t := &TypeCohesion{
+		Type:       path + "." + s.name,
+		Package:    path,
+		File:       s.file,
+		Line:       s.line,
+		Methods:    len(methods),
+		Fields:     len(s.fields),
+		Components: [][]string{},
+	}
+	isField := make(map[string]bool, len(s.fields))
+	for _, field := range s.fields {
+		isField[field] = true
+	}
+	index := make(map[string]int, len(methods))
+	for i, m := range methods {
+		index[m.name] = i
+	}
+
+	// Union the methods using a field with its first user, and the methods
+	// calling each other
+	parent := make([]int, len(methods))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) { parent[find(i)] = find(j) }
+
+	users := make(map[string]int)
+	for i, m := range methods {
+		for _, name := range m.selectors {
+			if isField[name] {
+				if j, ok := users[name]; ok {
+					union(i, j)
+				} else {
+					users[name] = i
+				}
+			} else if j, ok := index[name]; ok {
+				union(i, j)
+			}
+		}
+	}
+
+	components := make(map[int][]string)
+	for i, m := range methods {
+		root := find(i)
+		components[root] = append(components[root], m.name)
+	}
+	for _, names := range components {
+		sort.Strings(names)
+		t.Components = append(t.Components, names)
+	}
+	sort.Slice(t.Components, func(i, j int) bool {
+		if len(t.Components[i]) != len(t.Components[j]) {
+			return len(t.Components[i]) > len(t.Components[j])
+		}
+		return t.Components[i][0] < t.Components[j][0]
+	})
+	t.LCOM4 = len(t.Components)
+	t.GodType = t.Methods >= GodTypeMethods || t.Fields >= GodTypeFields && t.LCOM4 > 1
+	return t
+}
+
+// embeddedName returns the type name of an embedded field or receiver type,
+// without pointer, package qualifier or type arguments.
+func embeddedName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			return e.Sel.Name
+		case *ast.Ident:
+			return e.Name
+		default:
+			return types.ExprString(expr)
+		}
+	}
+}
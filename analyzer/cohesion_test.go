@@ -0,0 +1,168 @@
+package analyzer
+
+import (
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const cohesionSource = `package example
+
+import "sync"
+
+// Counter is cohesive: every method uses n or calls a method that does.
+type Counter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c *Counter) Inc()       { c.mu.Lock(); c.n++; c.mu.Unlock() }
+func (c *Counter) Get() int   { return c.n }
+func (c *Counter) Reset()     { c.set(0) }
+func (c *Counter) set(n int)  { c.n = n }
+
+// Split has two groups of methods sharing nothing.
+type Split struct {
+	name  string
+	count int
+}
+
+func (s Split) Name() string  { return s.name }
+func (s Split) Title() string { return s.Name() }
+func (s Split) Count() int    { return s.count }
+func (Split) Static()         {}
+
+// Shadowed selects fields of another value of the same name.
+type Shadowed struct {
+	a, b int
+}
+
+func (s *Shadowed) A() int { return s.a }
+func (s *Shadowed) B() int {
+	if s := (&Shadowed{}); s != nil {
+		return s.a
+	}
+	return s.b
+}
+
+// Embedded uses the mutex through promoted methods.
+type Embedded struct {
+	sync.Mutex
+	n int
+}
+
+func (e *Embedded) Lock2() { e.Lock() }
+func (e *Embedded) Inc()   { e.Unlock(); e.n++ }
+
+type Empty struct{}
+
+type Generic[T any] struct{ v T }
+
+func (g *Generic[T]) Get() T  { return g.v }
+func (g *Generic[T]) Set(v T) { g.v = v }
+`
+
+// cohesionOf returns the cohesion metrics of src by type name, with type
+// information when typed.
+func cohesionOf(t *testing.T, src string, typed bool) map[string]*TypeCohesion {
+	t.Helper()
+	fset := token.NewFileSet()
+	fa, err := ParseFile(fset, "example.go", []byte(src))
+	if err != nil {
+		t.Fatalf("parsing source: %v", err)
+	}
+	fa.SetPackagePath("example")
+	if typed {
+		for _, pkg := range LoadPackages(fset, []*FileAnalyzer{fa}, StdImporter()) {
+			if len(pkg.Errors) > 0 {
+				t.Fatalf("type-checking source: %v", pkg.Errors)
+			}
+		}
+	}
+	types := make(map[string]*TypeCohesion)
+	for _, tc := range Cohesion([]*FileAnalyzer{fa}) {
+		types[strings.TrimPrefix(tc.Type, "example.")] = tc
+	}
+	return types
+}
+
+func TestCohesion(t *testing.T) {
+	tests := []struct {
+		name       string
+		typed      bool
+		methods    int
+		fields     int
+		components [][]string
+	}{
+		{"Counter", false, 4, 2, [][]string{{"Get", "Inc", "Reset", "set"}}},
+		{"Split", false, 4, 2, [][]string{{"Name", "Title"}, {"Count"}, {"Static"}}},
+		{"Shadowed", false, 2, 2, [][]string{{"A", "B"}}},
+		{"Shadowed", true, 2, 2, [][]string{{"A"}, {"B"}}},
+		{"Embedded", false, 2, 2, [][]string{{"Inc"}, {"Lock2"}}},
+		{"Embedded", true, 2, 2, [][]string{{"Inc", "Lock2"}}},
+		{"Empty", false, 0, 0, [][]string{}},
+		{"Generic", true, 2, 1, [][]string{{"Get", "Set"}}},
+	}
+	untyped := cohesionOf(t, cohesionSource, false)
+	typed := cohesionOf(t, cohesionSource, true)
+	for _, tt := range tests {
+		name := tt.name
+		if tt.typed {
+			name += " typed"
+		}
+		t.Run(name, func(t *testing.T) {
+			tc := untyped[tt.name]
+			if tt.typed {
+				tc = typed[tt.name]
+			}
+			if tc == nil {
+				t.Fatalf("%s not reported", tt.name)
+			}
+			if tc.Methods != tt.methods || tc.Fields != tt.fields {
+				t.Errorf("methods, fields = %d, %d, want %d, %d", tc.Methods, tc.Fields, tt.methods, tt.fields)
+			}
+			if !reflect.DeepEqual(tc.Components, tt.components) {
+				t.Errorf("components = %v, want %v", tc.Components, tt.components)
+			}
+			if tc.LCOM4 != len(tt.components) {
+				t.Errorf("LCOM4 = %d, want %d", tc.LCOM4, len(tt.components))
+			}
+		})
+	}
+}
+
+func TestGodType(t *testing.T) {
+	tests := []struct {
+		name    string
+		methods int
+		fields  int
+		linked  bool // Whether every method uses the first field.
+		want    bool
+	}{
+		{"small", 3, 3, false, false},
+		{"many methods", GodTypeMethods, 1, true, true},
+		{"many cohesive fields", 2, GodTypeFields, true, false},
+		{"many fields split", 2, GodTypeFields, false, true},
+		{"data type", 0, GodTypeFields * 2, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := structFacts{name: "T"}
+			for i := 0; i < tt.fields; i++ {
+				s.fields = append(s.fields, "f"+strings.Repeat("x", i))
+			}
+			var methods []methodFacts
+			for i := 0; i < tt.methods; i++ {
+				m := methodFacts{recv: "T", name: "M" + strings.Repeat("x", i)}
+				if tt.linked {
+					m.selectors = []string{s.fields[0]}
+				}
+				methods = append(methods, m)
+			}
+			if got := typeCohesion("example", s, methods).GodType; got != tt.want {
+				t.Errorf("GodType = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package analyzer
+
+import "math"
+
+// Delta states reported in Delta.Status.
+const (
+	DeltaAdded    = "added"
+	DeltaRemoved  = "removed"
+	DeltaModified = "modified"
+)
+
+// Delta is the change of one function between two analyses. The metric
+// fields hold new minus old; for added and removed functions the missing
+// side counts as zero.
+type Delta struct {
+	Status          string         `json:"status"`            // added, removed or modified.
+	Name            string         `json:"name"`              // Qualified name, in the new analysis unless removed.
+	OldName         string         `json:"oldName,omitempty"` // Qualified name before a rename.
+	Old             *MetricsResult `json:"old,omitempty"`
+	New             *MetricsResult `json:"new,omitempty"`
+	Cyclomatic      int            `json:"cyclomatic"`
+	Cognitive       int            `json:"cognitive"`
+	Maintainability float64        `json:"maintainability"`
+	LinesOfCode     int            `json:"linesOfCode"`
+	HalsteadEffort  float64        `json:"halsteadEffort"`
+	NestedDepth     int            `json:"nestedDepth"`
+}
+
+// Compare returns the deltas of the functions that were added, removed or
+// whose metrics changed between old and new, matched with MatchFunctions.
+// Functions whose metrics are unchanged are left out, even when renamed.
+func Compare(old, new []*MetricsResult) []Delta {
+	var deltas []Delta
+	for _, match := range MatchFunctions(old, new) {
+		delta := Delta{Old: match.Old, New: match.New}
+		var before, after MetricsResult
+		switch {
+		case match.Old == nil:
+			delta.Status = DeltaAdded
+			delta.Name = match.New.QualifiedName
+			after = *match.New
+		case match.New == nil:
+			delta.Status = DeltaRemoved
+			delta.Name = match.Old.QualifiedName
+			before = *match.Old
+		default:
+			delta.Status = DeltaModified
+			delta.Name = match.New.QualifiedName
+			if match.Old.QualifiedName != match.New.QualifiedName {
+				delta.OldName = match.Old.QualifiedName
+			}
+			before, after = *match.Old, *match.New
+		}
+
+		delta.Cyclomatic = after.CyclomaticComplexity - before.CyclomaticComplexity
+		delta.Cognitive = after.CognitiveComplexity - before.CognitiveComplexity
+		delta.Maintainability = math.Round((after.MaintainabilityIndex-before.MaintainabilityIndex)*100) / 100
+		delta.LinesOfCode = after.LinesOfCode - before.LinesOfCode
+		delta.HalsteadEffort = math.Round((after.HalsteadEffort-before.HalsteadEffort)*100) / 100
+		delta.NestedDepth = after.NestedDepth - before.NestedDepth
+
+		if delta.Status == DeltaModified && delta.unchanged() {
+			continue
+		}
+		deltas = append(deltas, delta)
+	}
+	return deltas
+}
+
+func (d Delta) unchanged() bool {
+	return d.Cyclomatic == 0 && d.Cognitive == 0 && d.Maintainability == 0 &&
+		d.LinesOfCode == 0 && d.HalsteadEffort == 0 && d.NestedDepth == 0
+}
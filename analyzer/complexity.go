@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
+	"go/types"
 	"math"
 )
 
@@ -21,18 +22,44 @@ type Operand struct {
 
 // MetricsResult stores the complexity metrics for a single file or function
 type MetricsResult struct {
-	Name                 string  `json:"name"`
-	CyclomaticComplexity int     `json:"cyclomaticComplexity"` // Cyclomatic complexity of the function.
-	CognitiveComplexity  int     `json:"cognitiveComplexity"`  // Cognitive complexity of the function.
-	LinesOfCode          int     `json:"linesOfCode"`          // Lines of code in the function.
-	HalsteadVolume       float64 `json:"halsteadVolume"`       // Halstead volume of the function.
-	HalsteadDifficulty   float64 `json:"halsteadDifficulty"`   // Halstead difficulty of the function.
-	HalsteadEffort       float64 `json:"halsteadEffort"`       // Halstead effort of the function.
-	MaintainabilityIndex float64 `json:"maintainabilityIndex"` // Maintainability index of the function.
-	NestedDepth          int     `json:"nestedDepth"`          // Nested depth of the function.
-	CommentDensity       float64 `json:"commentDensity"`       // Comment density of the function.
-	FunctionParameters   int     `json:"functionParameters"`   // Number of function parameters.
-	ReturnStatements     int     `json:"returnStatements"`     // Number of return statements.
+	Name                 string               `json:"name"`
+	QualifiedName        string               `json:"qualifiedName"`                 // Package path, receiver and type parameters plus name.
+	Package              string               `json:"package"`                       // Import path of the enclosing package.
+	Kind                 string               `json:"kind"`                          // func, method or closure.
+	Receiver             string               `json:"receiver,omitempty"`            // Receiver type of a method, e.g. *Server.
+	Parent               string               `json:"parent,omitempty"`              // Qualified name of the unit enclosing a closure.
+	Fingerprint          string               `json:"fingerprint,omitempty"`         // Hash of the signature and body, used to follow renamed functions.
+	File                 string               `json:"file"`                          // File containing the function.
+	Classification       string               `json:"classification"`                // Class of the file: source, test, generated, vendor or testdata.
+	Line                 int                  `json:"line"`                          // Line of the func keyword.
+	Column               int                  `json:"column"`                        // Column of the func keyword.
+	EndLine              int                  `json:"endLine"`                       // Line of the closing brace.
+	EndColumn            int                  `json:"endColumn"`                     // Column just after the closing brace.
+	Offset               int                  `json:"offset"`                        // Byte offset of the func keyword.
+	EndOffset            int                  `json:"endOffset"`                     // Byte offset just after the closing brace.
+	CyclomaticComplexity int                  `json:"cyclomaticComplexity"`          // Cyclomatic complexity of the function.
+	NPathComplexity      int64                `json:"npathComplexity"`               // Number of acyclic execution paths, saturated at MaxNPath.
+	CognitiveComplexity  int                  `json:"cognitiveComplexity"`           // Cognitive complexity of the function.
+	CognitiveIncrements  []CognitiveIncrement `json:"cognitiveIncrements,omitempty"` // Increments that make up the cognitive complexity.
+	LinesOfCode          int                  `json:"linesOfCode"`                   // Lines of code in the function.
+	HalsteadVolume       float64              `json:"halsteadVolume"`                // Halstead volume of the function.
+	HalsteadDifficulty   float64              `json:"halsteadDifficulty"`            // Halstead difficulty of the function.
+	HalsteadEffort       float64              `json:"halsteadEffort"`                // Halstead effort of the function.
+	MaintainabilityIndex float64              `json:"maintainabilityIndex"`          // Maintainability index of the function.
+	NestedDepth          int                  `json:"nestedDepth"`                   // Nested depth of the function.
+	CommentDensity       float64              `json:"commentDensity"`                // Comment density of the function.
+	FunctionParameters   int                  `json:"functionParameters"`            // Number of function parameters.
+	ReturnStatements     int                  `json:"returnStatements"`              // Number of return statements.
+	InterfaceCalls       int                  `json:"interfaceCalls,omitempty"`      // Calls dispatched through an interface, when type-checked.
+	FanIn                int                  `json:"fanIn,omitempty"`               // Distinct callers, set by LinkCalls.
+	FanOut               int                  `json:"fanOut,omitempty"`              // Distinct callees, set by LinkCalls.
+	InformationFlow      int64                `json:"informationFlow,omitempty"`     // Henry-Kafura complexity: lines of code * (fan-in * fan-out)^2.
+	Suppressions         []Suppression        `json:"suppressions,omitempty"`        // complexity:ignore directives that apply.
+	Folded               bool                 `json:"folded,omitempty"`              // A closure also counted in its parent's metrics.
+
+	object *types.Func // Declared function, when type-checked.
+	calls  []call      // Resolved calls, when type-checked.
+	lines  int         // Lines of code for the information flow, even when not reported.
 }
 
 // CalculateCyclomaticComplexity calculates the cyclomatic complexity.
@@ -43,7 +70,7 @@ func (fa *FileAnalyzer) CalculateCyclomaticComplexity(node ast.Node) int {
 
 	complexity := 1
 
-	ast.Inspect(node, func(n ast.Node) bool {
+	ast.Inspect(node, fa.visitor(node, func(n ast.Node) bool {
 		switch n := n.(type) {
 		case *ast.IfStmt:
 			complexity++
@@ -94,73 +121,163 @@ func (fa *FileAnalyzer) CalculateCyclomaticComplexity(node ast.Node) int {
 			}
 		}
 		return true
-	})
+	}))
 
 	return complexity
 }
 
-// CalculateCognitiveComplexity calculates the cognitive complexity of a given node.
-func (fa *FileAnalyzer) CalculateCognitiveComplexity(node ast.Node) int {
-	if node == nil {
-		return 0
+// MaxNPath is the value NPath complexity saturates at. It is the largest
+// integer a JavaScript number represents exactly.
+const MaxNPath int64 = 1<<53 - 1
+
+// CalculateNPathComplexity calculates the NPath complexity of a given node,
+// the number of acyclic execution paths as defined by Nejmeh. Folded
+// closures multiply the paths of their enclosing function by their own, as
+// if each ran once.
+func (fa *FileAnalyzer) CalculateNPathComplexity(node ast.Node) int64 {
+	var body *ast.BlockStmt
+	switch n := node.(type) {
+	case *ast.FuncDecl:
+		body = n.Body
+	case *ast.FuncLit:
+		body = n.Body
+	case *ast.BlockStmt:
+		body = n
+	}
+	if body == nil {
+		return 1
 	}
 
-	complexity := 0
-	nestingLevel := 0
+	paths := npathStmts(body.List)
+	ast.Inspect(body, fa.visitor(node, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.FuncLit); ok && n != node {
+			paths = npathMul(paths, npathStmts(lit.Body.List))
+		}
+		return true
+	}))
+	return paths
+}
 
-	var inspect func(ast.Node) bool
-	inspect = func(n ast.Node) bool {
-		switch n := n.(type) {
-		case *ast.IfStmt:
-			complexity += 1 + nestingLevel
-			nestingLevel++
-			ast.Inspect(n.Body, inspect)
-			if n.Else != nil {
-				if _, isElseIf := n.Else.(*ast.IfStmt); !isElseIf {
-					complexity++
-				}
-				ast.Inspect(n.Else, inspect)
+// npathStmts returns the NPath of a sequence: the product of its statements.
+func npathStmts(stmts []ast.Stmt) int64 {
+	paths := int64(1)
+	for _, stmt := range stmts {
+		paths = npathMul(paths, npathStmt(stmt))
+	}
+	return paths
+}
+
+// npathStmt returns the NPath of a single statement.
+func npathStmt(stmt ast.Stmt) int64 {
+	switch s := stmt.(type) {
+	case *ast.BlockStmt:
+		return npathStmts(s.List)
+	case *ast.LabeledStmt:
+		return npathStmt(s.Stmt)
+	case *ast.IfStmt:
+		// NP(cond) + NP(then) + NP(else), where a missing else counts 1.
+		elsePaths := int64(1)
+		if s.Else != nil {
+			elsePaths = npathStmt(s.Else)
+		}
+		return npathAdd(npathAdd(npathExpr(s.Cond), npathStmts(s.Body.List)), elsePaths)
+	case *ast.ForStmt:
+		return npathAdd(npathAdd(npathExpr(s.Cond), npathStmts(s.Body.List)), 1)
+	case *ast.RangeStmt:
+		return npathAdd(npathStmts(s.Body.List), 1)
+	case *ast.SwitchStmt:
+		return npathAdd(npathExpr(s.Tag), npathClauses(s.Body))
+	case *ast.TypeSwitchStmt:
+		return npathClauses(s.Body)
+	case *ast.SelectStmt:
+		return npathClauses(s.Body)
+	case *ast.ReturnStmt:
+		paths := int64(0)
+		for _, result := range s.Results {
+			paths = npathAdd(paths, npathExpr(result))
+		}
+		if paths == 0 {
+			return 1
+		}
+		return paths
+	}
+	return 1
+}
+
+// npathClauses returns the NPath of the clauses of a switch, type switch or
+// select statement. A switch without default has one more path that skips
+// every clause; a select without default blocks instead.
+func npathClauses(body *ast.BlockStmt) int64 {
+	paths := int64(0)
+	hasDefault := false
+	isSelect := false
+	for _, stmt := range body.List {
+		switch clause := stmt.(type) {
+		case *ast.CaseClause:
+			if clause.List == nil {
+				hasDefault = true
 			}
-			nestingLevel--
-			return false
+			for _, expr := range clause.List {
+				paths = npathAdd(paths, npathExpr(expr))
+			}
+			paths = npathAdd(paths, npathStmts(clause.Body))
+		case *ast.CommClause:
+			isSelect = true
+			paths = npathAdd(paths, npathStmts(clause.Body))
+		}
+	}
+	if !hasDefault && !isSelect {
+		paths = npathAdd(paths, 1)
+	}
+	if paths == 0 {
+		return 1
+	}
+	return paths
+}
 
-		case *ast.ForStmt:
-			complexity += 1 + nestingLevel
-			nestingLevel++
-			ast.Inspect(n.Body, inspect)
-			nestingLevel--
-			return false
-		case *ast.RangeStmt:
-			complexity += 1 + nestingLevel
-			nestingLevel++
-			ast.Inspect(n.Body, inspect)
-			nestingLevel--
-			return false
-		case *ast.SwitchStmt:
-			complexity += 1 + nestingLevel
-			nestingLevel++
-			ast.Inspect(n.Body, inspect)
-			nestingLevel--
-			return false
-		case *ast.TypeSwitchStmt:
-			complexity += 1 + nestingLevel
-			nestingLevel++
-			ast.Inspect(n.Body, inspect)
-			nestingLevel--
-			return false
-		case *ast.SelectStmt:
-			complexity += 1 + nestingLevel
-			nestingLevel++
-			ast.Inspect(n.Body, inspect)
-			nestingLevel--
+// npathExpr returns the number of && and || operators in an expression,
+// ignoring function literals.
+func npathExpr(expr ast.Expr) int64 {
+	if expr == nil {
+		return 0
+	}
+	count := int64(0)
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				count++
+			}
+		case *ast.FuncLit:
 			return false
-		case *ast.FuncLit: // closure
-			complexity++
 		}
 		return true
+	})
+	return count
+}
+
+// npathAdd adds two path counts, saturating at MaxNPath.
+func npathAdd(a, b int64) int64 {
+	if a > MaxNPath-b {
+		return MaxNPath
 	}
+	return a + b
+}
 
-	ast.Inspect(node, inspect)
+// npathMul multiplies two path counts, saturating at MaxNPath.
+func npathMul(a, b int64) int64 {
+	if a != 0 && b > MaxNPath/a {
+		return MaxNPath
+	}
+	return a * b
+}
+
+// CalculateCognitiveComplexity calculates the cognitive complexity of a given node.
+func (fa *FileAnalyzer) CalculateCognitiveComplexity(node ast.Node) int {
+	complexity := 0
+	for _, increment := range fa.ExplainCognitiveComplexity(node) {
+		complexity += increment.Base + increment.Nesting
+	}
 	return complexity
 }
 
@@ -170,7 +287,7 @@ func (fa *FileAnalyzer) calculateHalsteadMetrics(node ast.Node) (volume, difficu
 		return 0, 0, 0
 	}
 
-	temp, err := CalculateHalsteadMetrics(node)
+	temp, err := halsteadMetrics(node, fa.visitor, fa.info)
 	if err != nil {
 		fmt.Println(err)
 		return 0, 0, 0
@@ -205,7 +322,7 @@ func (fa *FileAnalyzer) calculateNestedDepth(node ast.Node) int {
 	currentDepth := 0
 
 	var inspect func(ast.Node) bool
-	inspect = func(n ast.Node) bool {
+	inspect = fa.visitor(node, func(n ast.Node) bool {
 		switch n.(type) {
 		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.SelectStmt:
 			currentDepth++
@@ -246,7 +363,7 @@ func (fa *FileAnalyzer) calculateNestedDepth(node ast.Node) int {
 		}
 
 		return true
-	}
+	})
 
 	ast.Inspect(node, inspect)
 	return maxDepth
@@ -279,16 +396,12 @@ func (fa *FileAnalyzer) countReturnStatements(node ast.Node) int {
 	}
 
 	count := 0
-	ast.Inspect(node, func(n ast.Node) bool {
-		switch n.(type) {
-		case *ast.ReturnStmt:
+	ast.Inspect(node, fa.visitor(node, func(n ast.Node) bool {
+		if _, ok := n.(*ast.ReturnStmt); ok {
 			count++
-		case *ast.FuncLit:
-			// we don't want to count return statements inside a closure
-			return false // Stop traversing this node.
 		}
 		return true // Continue traversing other nodes.
-	})
+	}))
 
 	return count
 }
@@ -307,7 +420,7 @@ func (fa *FileAnalyzer) CountLinesOfCode(node ast.Node) int {
 	}
 
 	var docStringComments int
-	if nd := node.(*ast.FuncDecl); nd != nil {
+	if nd, ok := node.(*ast.FuncDecl); ok {
 		if nd.Doc != nil {
 			startDoc := fa.fset.Position(nd.Doc.Pos())
 			endDoc := fa.fset.Position(nd.Doc.End())
@@ -0,0 +1,77 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNPathComplexity(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int64
+	}{
+		{"empty", ``, 1},
+		{"if", `if a {}`, 2},
+		{"if else", `if a {} else {}`, 2},
+		{"else if", `if a {} else if b {}`, 3},
+		{"condition", `if a && b || c {}`, 4},
+		{"sequence", `if a {}; if b {}`, 4},
+		{"nested", `if a { if b {} }`, 3},
+		{"for", `for a {}`, 2},
+		{"range", `for range xs { if a {} }`, 3},
+		{"switch", `switch x { case 1: case 2: }`, 3},
+		{"switch default", `switch x { case 1: default: }`, 2},
+		{"select", `select { case <-ch: case <-ch: }`, 2},
+		{"return", `return a || b`, 1},
+		{"closure", `f := func() { if a {} }; f()`, 1},
+		{"saturated", strings.Repeat(`if a {}; `, 60), MaxNPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "package example\n\nfunc f(a, b, c bool, x int, xs []int, ch chan int) bool {\n" + tt.body + "\nreturn false\n}\n"
+			result := analyzeSource(t, src)["f"]
+			if result.NPathComplexity != tt.want {
+				t.Errorf("NPath = %d, want %d", result.NPathComplexity, tt.want)
+			}
+		})
+	}
+}
+
+func TestNPathFoldedClosures(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		fold bool
+		want int64
+	}{
+		{"unfolded", `f := func() { if a {} }; f()`, false, 1},
+		{"folded", `f := func() { if a {} }; f()`, true, 2},
+		{"folded with parent paths", `if b {}; f := func() { if a {} else if c {} }; f()`, true, 6},
+		{"folded nested", `f := func() { if a {}; g := func() { if b {} }; g() }; f()`, true, 4},
+		{"folded in condition", `if func() bool { if a { return true }; return false }() {}`, true, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "package example\n\nfunc f(a, b, c bool) {\n" + tt.body + "\n}\n"
+			fa, err := NewFileAnalyzer("example.go", []byte(src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			fa.SetFoldClosures(tt.fold)
+			fa.SetPackagePath("example")
+			var result *MetricsResult
+			for _, r := range fa.AnalyzeFile() {
+				if r.Name == "f" {
+					result = r
+				}
+			}
+			if result == nil {
+				t.Fatal("no result for f")
+			}
+			if result.NPathComplexity != tt.want {
+				t.Errorf("NPath = %d, want %d", result.NPathComplexity, tt.want)
+			}
+		})
+	}
+}
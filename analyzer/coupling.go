@@ -0,0 +1,140 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// PackageMetrics holds Robert Martin's coupling metrics of a package.
+// Dependencies only count the packages analyzed together, so that the
+// standard library and third-party modules do not make every package look
+// unstable, and test files are left out, so that imports used only by tests
+// do not either.
+//
+// Instability is undefined for a package without dependencies or
+// dependents, abstractness for a package without types, and the distance
+// when either is; undefined metrics are nil.
+type PackageMetrics struct {
+	Package      string   `json:"package"`
+	Afferent     int      `json:"afferent"`     // Ca: analyzed packages importing this one.
+	Efferent     int      `json:"efferent"`     // Ce: analyzed packages this one imports.
+	Instability  *float64 `json:"instability"`  // Ce / (Ca + Ce).
+	Abstractness *float64 `json:"abstractness"` // Interfaces / declared types.
+	Distance     *float64 `json:"distance"`     // |A + I - 1|, distance from the main sequence.
+	Types        int      `json:"types"`        // Named types declared at package level, aliases excluded.
+	Interfaces   int      `json:"interfaces"`   // Interface types among them.
+	Dependencies []string `json:"dependencies"` // Import paths of the efferent packages.
+	Dependents   []string `json:"dependents"`   // Import paths of the afferent packages.
+}
+
+// fileFacts is what a file contributes to the coupling and cohesion
+// metrics of its package.
+type fileFacts struct {
+	test       bool // Left out of the coupling metrics.
+	imports    []string
+	types      int
+	interfaces int
+	structs    []structFacts
+	methods    []methodFacts
+}
+
+// Coupling computes the coupling metrics of the packages of files, grouped
+// by PackagePath, sorted by import path.
+func Coupling(files []*FileAnalyzer) []*PackageMetrics {
+	facts := make(map[string][]fileFacts)
+	for _, fa := range files {
+		facts[fa.PackagePath()] = append(facts[fa.PackagePath()], fa.facts())
+	}
+	return coupling(facts)
+}
+
+// facts collects the imports, type declarations and methods of the file.
+func (fa *FileAnalyzer) facts() fileFacts {
+	facts := fileFacts{test: fa.class == ClassTest, structs: fa.structs(), methods: fa.methods()}
+	for _, spec := range fa.ast.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+			facts.imports = append(facts.imports, path)
+		}
+	}
+	for _, decl := range fa.ast.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			if typeSpec.Assign.IsValid() {
+				continue
+			}
+			facts.types++
+			if _, ok := typeSpec.Type.(*ast.InterfaceType); ok {
+				facts.interfaces++
+			}
+		}
+	}
+	return facts
+}
+
+// coupling computes the metrics of the packages described by facts, keyed
+// by import path. Packages made of test files only are left out.
+func coupling(facts map[string][]fileFacts) []*PackageMetrics {
+	packages := make(map[string]*PackageMetrics, len(facts))
+	for path, files := range facts {
+		for _, file := range files {
+			if !file.test {
+				packages[path] = &PackageMetrics{Package: path, Dependencies: []string{}, Dependents: []string{}}
+				break
+			}
+		}
+	}
+
+	for path, pkg := range packages {
+		seen := make(map[string]bool)
+		for _, file := range facts[path] {
+			if file.test {
+				continue
+			}
+			pkg.Types += file.types
+			pkg.Interfaces += file.interfaces
+			for _, imp := range file.imports {
+				dep, ok := packages[imp]
+				if !ok || imp == path || seen[imp] {
+					continue
+				}
+				seen[imp] = true
+				pkg.Dependencies = append(pkg.Dependencies, imp)
+				dep.Dependents = append(dep.Dependents, path)
+			}
+		}
+	}
+
+	metrics := make([]*PackageMetrics, 0, len(packages))
+	for _, pkg := range packages {
+		sort.Strings(pkg.Dependencies)
+		sort.Strings(pkg.Dependents)
+		pkg.Afferent = len(pkg.Dependents)
+		pkg.Efferent = len(pkg.Dependencies)
+		if pkg.Afferent+pkg.Efferent > 0 {
+			pkg.Instability = ratio(pkg.Efferent, pkg.Afferent+pkg.Efferent)
+		}
+		if pkg.Types > 0 {
+			pkg.Abstractness = ratio(pkg.Interfaces, pkg.Types)
+		}
+		if pkg.Instability != nil && pkg.Abstractness != nil {
+			distance := math.Round(math.Abs(*pkg.Abstractness+*pkg.Instability-1)*100) / 100
+			pkg.Distance = &distance
+		}
+		metrics = append(metrics, pkg)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Package < metrics[j].Package })
+	return metrics
+}
+
+// ratio returns n / d rounded to 2 decimals.
+func ratio(n, d int) *float64 {
+	r := math.Round(float64(n)/float64(d)*100) / 100
+	return &r
+}
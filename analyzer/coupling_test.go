@@ -0,0 +1,119 @@
+package analyzer
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"testing"
+)
+
+// parseFiles parses sources keyed by file name, each in the package of its
+// directory under the module example.
+func parseFiles(t *testing.T, sources map[string]string) []*FileAnalyzer {
+	t.Helper()
+	var files []*FileAnalyzer
+	for name, src := range sources {
+		fa, err := NewFileAnalyzer(name, []byte(src))
+		if err != nil {
+			t.Fatalf("parsing %s: %v", name, err)
+		}
+		fa.SetPackagePath(sourceImportPath(path.Dir(name), map[string]string{".": "example"}))
+		files = append(files, fa)
+	}
+	return files
+}
+
+func TestCoupling(t *testing.T) {
+	files := parseFiles(t, map[string]string{
+		"api/api.go": `package api
+
+type Store interface{ Get(string) Item }
+
+type Item struct{}
+
+type Alias = Item
+`,
+		"db/db.go": `package db
+
+import (
+	"fmt"
+
+	"example/api"
+)
+
+type DB struct{ store api.Store }
+
+var _ = fmt.Sprint
+`,
+		"app/app.go": `package app
+
+import (
+	"example/api"
+	"example/db"
+)
+
+type App struct {
+	db    *db.DB
+	store api.Store
+}
+`,
+		"app/app_test.go": `package app
+
+import "example/mock"
+
+var _ mock.Mock
+`,
+		"mock/mock.go": `package mock
+
+type Mock struct{}
+`,
+		"isolated/isolated.go": `package isolated
+
+func F() {}
+`,
+		"testonly/testonly_test.go": `package testonly
+
+import "example/api"
+
+var _ api.Item
+`,
+	})
+
+	type metrics struct {
+		Afferent, Efferent int
+		I, A, D            string
+		Types, Interfaces  int
+		Dependencies       []string
+	}
+	want := map[string]metrics{
+		"example/api":      {2, 0, "0.00", "0.50", "0.50", 2, 1, []string{}},
+		"example/app":      {0, 2, "1.00", "0.00", "0.00", 1, 0, []string{"example/api", "example/db"}},
+		"example/db":       {1, 1, "0.50", "0.00", "0.50", 1, 0, []string{"example/api"}},
+		"example/isolated": {0, 0, "N/A", "N/A", "N/A", 0, 0, []string{}},
+		"example/mock":     {0, 0, "N/A", "0.00", "N/A", 1, 0, []string{}},
+	}
+
+	got := make(map[string]metrics)
+	for _, p := range Coupling(files) {
+		got[p.Package] = metrics{
+			p.Afferent, p.Efferent,
+			formatMetric(p.Instability), formatMetric(p.Abstractness), formatMetric(p.Distance),
+			p.Types, p.Interfaces, p.Dependencies,
+		}
+	}
+	for pkg, want := range want {
+		if !reflect.DeepEqual(got[pkg], want) {
+			t.Errorf("%s: got %+v, want %+v", pkg, got[pkg], want)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d packages, want %d: %v", len(got), len(want), got)
+	}
+}
+
+func formatMetric(value *float64) string {
+	if value == nil {
+		return "N/A"
+	}
+	return fmt.Sprintf("%.2f", *value)
+}
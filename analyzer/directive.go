@@ -0,0 +1,151 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"strconv"
+	"strings"
+)
+
+// ignoreDirective starts a comment suppressing threshold violations:
+//
+//	//complexity:ignore cyclomatic,nesting reason="generated state machine"
+//
+// It applies to the function whose doc comment holds it, including the
+// closures within, or to every function of the file when it appears before
+// the package clause. Without metrics it suppresses every metric.
+const ignoreDirective = "//complexity:ignore"
+
+// Ways a violation can be suppressed, reported in Violation.SuppressedBy.
+const (
+	SuppressedByDirective = "directive"
+	SuppressedByConfig    = "config"
+)
+
+// Suppression is a complexity:ignore directive that applies to a function.
+type Suppression struct {
+	File    string   `json:"file"`
+	Line    int      `json:"line"`              // Line of the directive.
+	Metrics []string `json:"metrics,omitempty"` // Suppressed metrics; all when empty.
+	Reason  string   `json:"reason,omitempty"`
+}
+
+// Covers reports whether the suppression applies to metric.
+func (s Suppression) Covers(metric string) bool {
+	if len(s.Metrics) == 0 {
+		return true
+	}
+	for _, m := range s.Metrics {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// fileSuppressions returns the directives in the comments preceding the
+// package clause.
+func (fa *FileAnalyzer) fileSuppressions() []Suppression {
+	var suppressions []Suppression
+	for _, group := range fa.ast.Comments {
+		if group.End() >= fa.ast.Package {
+			break
+		}
+		suppressions = append(suppressions, fa.suppressions(group)...)
+	}
+	return suppressions
+}
+
+// DirectiveErrors returns the problems of the file's complexity:ignore
+// directives, such as unknown metric names, prefixed with their position.
+// The directives still apply, but unknown metrics suppress nothing.
+func (fa *FileAnalyzer) DirectiveErrors() []error {
+	var errs []error
+	for _, group := range fa.ast.Comments {
+		_, groupErrs := fa.parseDirectives(group)
+		errs = append(errs, groupErrs...)
+	}
+	return errs
+}
+
+// suppressions returns the directives in a comment group.
+func (fa *FileAnalyzer) suppressions(group *ast.CommentGroup) []Suppression {
+	suppressions, _ := fa.parseDirectives(group)
+	return suppressions
+}
+
+// parseDirectives returns the directives in a comment group and their
+// problems.
+func (fa *FileAnalyzer) parseDirectives(group *ast.CommentGroup) ([]Suppression, []error) {
+	if group == nil {
+		return nil, nil
+	}
+
+	var suppressions []Suppression
+	var errs []error
+	for _, comment := range group.List {
+		args, ok := strings.CutPrefix(comment.Text, ignoreDirective)
+		if !ok || args != "" && args[0] != ' ' && args[0] != '\t' {
+			continue
+		}
+		pos := fa.fset.Position(comment.Pos())
+		s, err := parseIgnoreArgs(args)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %s: %w", pos.Filename, pos.Line, ignoreDirective[2:], err))
+		}
+		s.File, s.Line = pos.Filename, pos.Line
+		suppressions = append(suppressions, s)
+	}
+	return suppressions, errs
+}
+
+// parseIgnoreArgs parses the metrics, separated by spaces or commas, and the
+// optional reason="..." of a directive. The first unknown metric or an
+// unterminated reason is reported, but the directive is parsed regardless.
+func parseIgnoreArgs(args string) (Suppression, error) {
+	var s Suppression
+	var err error
+	report := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		if reason, ok := strings.CutPrefix(args, "reason="); ok {
+			if quoted, quoteErr := strconv.QuotedPrefix(reason); quoteErr == nil {
+				s.Reason, _ = strconv.Unquote(quoted)
+				args = reason[len(quoted):]
+			} else {
+				if strings.HasPrefix(reason, `"`) {
+					report(errors.New("unterminated reason"))
+				}
+				s.Reason, args, _ = strings.Cut(reason, " ")
+			}
+			continue
+		}
+
+		word, rest, _ := strings.Cut(args, " ")
+		for _, metric := range strings.Split(word, ",") {
+			if metric == "" {
+				continue
+			}
+			if !knownMetric(metric) {
+				report(fmt.Errorf("unknown metric %q", metric))
+			}
+			s.Metrics = append(s.Metrics, metric)
+		}
+		args = rest
+	}
+	return s, err
+}
+
+// knownMetric reports whether metric is one of Metrics.
+func knownMetric(metric string) bool {
+	for _, m := range Metrics {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIgnoreArgs(t *testing.T) {
+	tests := []struct {
+		args string
+		want Suppression
+		err  string
+	}{
+		{"", Suppression{}, ""},
+		{" cyclomatic", Suppression{Metrics: []string{"cyclomatic"}}, ""},
+		{" cyclomatic,nesting cognitive", Suppression{Metrics: []string{"cyclomatic", "nesting", "cognitive"}}, ""},
+		{` reason="generated state machine"`, Suppression{Reason: "generated state machine"}, ""},
+		{` nesting reason="a \"quoted\" reason"`, Suppression{Metrics: []string{"nesting"}, Reason: `a "quoted" reason`}, ""},
+		{" reason=legacy cyclomatic", Suppression{Metrics: []string{"cyclomatic"}, Reason: "legacy"}, ""},
+		{" cyclomatc,nesting", Suppression{Metrics: []string{"cyclomatc", "nesting"}}, `unknown metric "cyclomatc"`},
+		{` reason="unterminated`, Suppression{Reason: `"unterminated`}, "unterminated reason"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			got, err := parseIgnoreArgs(tt.args)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIgnoreArgs(%q) = %+v, want %+v", tt.args, got, tt.want)
+			}
+			errString := ""
+			if err != nil {
+				errString = err.Error()
+			}
+			if errString != tt.err {
+				t.Errorf("parseIgnoreArgs(%q) error = %v, want %q", tt.args, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSuppressions(t *testing.T) {
+	src := `//complexity:ignore returns reason="file"
+
+package example
+
+//complexity:ignore cyclomatic reason="function"
+func Ignored() {
+	_ = func() {}
+}
+
+// Unrelated mentions //complexity:ignore in the middle of a comment.
+func Mentioned() {}
+
+//complexity:ignored is another directive.
+func Misspelled() {}
+
+var initializer = func() {}
+`
+	file := Suppression{File: "example.go", Line: 1, Metrics: []string{"returns"}, Reason: "file"}
+	function := Suppression{File: "example.go", Line: 5, Metrics: []string{"cyclomatic"}, Reason: "function"}
+	tests := []struct {
+		name string
+		want []Suppression
+	}{
+		{"Ignored", []Suppression{file, function}},
+		{"Ignored.func1", []Suppression{file, function}},
+		{"Mentioned", []Suppression{file}},
+		{"Misspelled", []Suppression{file}},
+		{"init.func1", []Suppression{file}},
+	}
+	results := analyzeSource(t, src)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := results[tt.name]
+			if !ok {
+				t.Fatalf("%s not analyzed", tt.name)
+			}
+			if !reflect.DeepEqual(result.Suppressions, tt.want) {
+				t.Errorf("suppressions = %+v, want %+v", result.Suppressions, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuppressionCovers(t *testing.T) {
+	tests := []struct {
+		metrics []string
+		metric  string
+		want    bool
+	}{
+		{nil, MetricCyclomatic, true},
+		{[]string{MetricCyclomatic, MetricNesting}, MetricNesting, true},
+		{[]string{MetricCyclomatic}, MetricCognitive, false},
+	}
+	for _, tt := range tests {
+		s := Suppression{Metrics: tt.metrics}
+		if got := s.Covers(tt.metric); got != tt.want {
+			t.Errorf("%v covers %s = %v, want %v", tt.metrics, tt.metric, got, tt.want)
+		}
+	}
+}
+
+func TestDirectiveErrors(t *testing.T) {
+	src := `//complexity:ignore cyclomatic
+
+package example
+
+//complexity:ignore cyclomatc reason="typo"
+func Typo() {}
+
+//complexity:ignore nesting reason="unterminated
+func Unterminated() {}
+`
+	fa, err := NewFileAnalyzer("example.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, err := range fa.DirectiveErrors() {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		`example.go:5: complexity:ignore: unknown metric "cyclomatc"`,
+		`example.go:8: complexity:ignore: unterminated reason`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,99 @@
+package analyzer
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FindGoFiles expands the given patterns into a sorted list of Go source files.
+// A pattern may be a single file, a directory (its .go files only) or a
+// directory followed by "/..." to walk it recursively, mirroring the go tool.
+// Unlike the go tool, walks include vendor and testdata directories; use
+// ClassifyPath to tell their files apart.
+func FindGoFiles(patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	add := func(path string) {
+		path = filepath.Clean(path)
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, pattern := range patterns {
+		if root, ok := strings.CutSuffix(pattern, "..."); ok {
+			root = strings.TrimSuffix(root, "/")
+			if root == "" {
+				root = "."
+			}
+			if err := walkGoFiles(root, add); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		info, err := os.Stat(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if !isGoFile(pattern) {
+				return nil, fmt.Errorf("%s: not a .go file", pattern)
+			}
+			add(pattern)
+			continue
+		}
+
+		entries, err := os.ReadDir(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && isGoFile(entry.Name()) {
+				add(filepath.Join(pattern, entry.Name()))
+			}
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// walkGoFiles calls add for every Go file below root, skipping hidden
+// directories and those starting with an underscore.
+func walkGoFiles(root string, add func(string)) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isGoFile(d.Name()) {
+			add(path)
+		}
+		return nil
+	})
+}
+
+// skipDir reports whether a directory is excluded from recursive walks.
+func skipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// isGoFile reports whether name looks like a Go source file.
+func isGoFile(name string) bool {
+	return filepath.Ext(name) == ".go" && !strings.HasPrefix(filepath.Base(name), ".")
+}
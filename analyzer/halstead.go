@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
+	"go/types"
 	"math"
 )
 
@@ -22,131 +23,199 @@ type HalsteadMetrics struct {
 
 // CalculateHalsteadMetrics (updated)
 func CalculateHalsteadMetrics(node ast.Node) (HalsteadMetrics, error) {
+	return halsteadMetrics(node, func(_ ast.Node, f func(ast.Node) bool) func(ast.Node) bool { return f }, nil)
+}
+
+// halsteadMetrics counts operators and operands in node; visitor decides
+// which parts of the tree belong to it. info, if not nil, classifies
+// identifiers by what they denote.
+func halsteadMetrics(node ast.Node, visitor func(ast.Node, func(ast.Node) bool) func(ast.Node) bool, info *types.Info) (HalsteadMetrics, error) {
 	if node == nil {
 		return HalsteadMetrics{}, fmt.Errorf("input node cannot be nil")
 	}
 
-	operators := make(map[token.Token]int)
+	operators := make(map[string]int)
 	operands := make(map[string]int)
 
-	ast.Inspect(node, func(n ast.Node) bool {
+	countHalstead(node, visitor, info,
+		func(_ token.Pos, op string) { operators[op]++ },
+		func(_ token.Pos, name string) { operands[name]++ })
+
+	N1 := 0
+	for _, count := range operators {
+		N1 += count
+	}
+
+	N2 := 0
+	for _, count := range operands {
+		N2 += count
+	}
+
+	Eta1 := len(operators)
+	Eta2 := len(operands)
+
+	length := N1 + N2
+	vocabulary := Eta1 + Eta2
+
+	// Halstead calculations (with adjustments)
+	var volume, difficulty, effort float64
+
+	if vocabulary > 0 {
+		volume = float64(length) * math.Log2(float64(vocabulary))
+	}
+	if Eta2 > 0 {
+		difficulty = (float64(Eta1) / 2.0) * (float64(N2) / float64(Eta2))
+	}
+	effort = difficulty * volume
+
+	metrics := HalsteadMetrics{
+		N1:         N1,
+		N2:         N2,
+		Eta1:       Eta1,
+		Eta2:       Eta2,
+		Length:     length,
+		Vocabulary: vocabulary,
+		Volume:     volume,
+		Difficulty: difficulty,
+		Effort:     effort,
+	}
+
+	return metrics, nil
+}
+
+// countHalstead walks node and reports every operator and operand with its
+// position; visitor decides which parts of the tree belong to node.
+//
+// Without type information every identifier is an operand. With it, type
+// names, builtins and the functions and methods referred to are operators
+// named after what they denote, package qualifiers are not counted, and
+// variables are told apart by declaration rather than by name.
+func countHalstead(node ast.Node, visitor func(ast.Node, func(ast.Node) bool) func(ast.Node) bool, info *types.Info,
+	operator func(token.Pos, string), operand func(token.Pos, string)) {
+	op := func(pos token.Pos, tok token.Token) { operator(pos, tok.String()) }
+	ast.Inspect(node, visitor(node, func(n ast.Node) bool {
 		switch x := n.(type) {
 		// Operators
 		case *ast.BinaryExpr:
-			operators[x.Op]++
+			op(x.OpPos, x.Op)
 		case *ast.UnaryExpr:
-			operators[x.Op]++
+			op(n.Pos(), x.Op)
 		case *ast.CallExpr:
-			operators[token.FUNC]++
+			op(x.Lparen, token.FUNC)
 			if x.Ellipsis.IsValid() {
-				operators[token.ELLIPSIS]++
+				op(n.Pos(), token.ELLIPSIS)
 			}
 		case *ast.IncDecStmt:
-			operators[x.Tok]++
+			op(n.Pos(), x.Tok)
 		case *ast.AssignStmt:
-			operators[x.Tok]++
+			op(n.Pos(), x.Tok)
 		case *ast.ReturnStmt:
-			operators[token.RETURN]++
+			op(n.Pos(), token.RETURN)
 		case *ast.IfStmt:
-			operators[token.IF]++
+			op(n.Pos(), token.IF)
 		case *ast.ForStmt:
-			operators[token.FOR]++
+			op(n.Pos(), token.FOR)
 		case *ast.RangeStmt:
-			operators[token.RANGE]++
+			op(n.Pos(), token.RANGE)
 		case *ast.SwitchStmt:
-			operators[token.SWITCH]++
+			op(n.Pos(), token.SWITCH)
 		case *ast.CaseClause:
-			operators[token.CASE]++
+			op(n.Pos(), token.CASE)
 		case *ast.TypeSwitchStmt:
-			operators[token.SWITCH]++
+			op(n.Pos(), token.SWITCH)
 		case *ast.TypeAssertExpr:
-			operators[token.PERIOD]++
+			op(n.Pos(), token.PERIOD)
 		case *ast.SendStmt:
-			operators[token.ARROW]++
+			op(n.Pos(), token.ARROW)
 		case *ast.GoStmt:
-			operators[token.GO]++
+			op(n.Pos(), token.GO)
 		case *ast.DeferStmt:
-			operators[token.DEFER]++
+			op(n.Pos(), token.DEFER)
 		case *ast.BranchStmt:
-			operators[x.Tok]++
+			op(n.Pos(), x.Tok)
 		case *ast.SelectorExpr:
-			operators[token.PERIOD]++
+			// A package-qualified identifier is a single name
+			if !isPackageName(info, x.X) {
+				op(n.Pos(), token.PERIOD)
+			}
 		//Parenthesis are not counted as operators in many implementations
 		//Operands
 		case *ast.Ident:
-			if x.Obj != nil && x.Obj.Kind == ast.Typ {
-				operands[x.Name]++
-			} else {
-				operands[x.Name]++
-			}
+			countIdent(info, x, operator, operand)
 		case *ast.BasicLit:
-			operands[x.Value]++
+			operand(n.Pos(), x.Value)
 		case *ast.CompositeLit:
 			if x.Type != nil {
 				if _, ok := x.Type.(*ast.Ellipsis); ok {
-					operators[token.ELLIPSIS]++
-				} else if id, ok := x.Type.(*ast.Ident); ok {
-					operands[id.Name]++
+					op(n.Pos(), token.ELLIPSIS)
+				} else if id, ok := x.Type.(*ast.Ident); ok && info == nil {
+					operand(id.Pos(), id.Name)
 				}
 
 			}
 		case *ast.FuncDecl:
 			//Function name as an operand
-			operands[x.Name.Name]++
+			operand(x.Name.Pos(), x.Name.Name)
 
 		case *ast.ChanType:
-			operands["chan"]++
+			operand(n.Pos(), "chan")
 
 		case *ast.FuncType:
 			if x.Params != nil {
 				for _, field := range x.Params.List {
 					if _, ok := field.Type.(*ast.Ellipsis); ok {
-						operators[token.ELLIPSIS]++
+						op(field.Type.Pos(), token.ELLIPSIS)
 					}
 				}
 			}
 		}
 		return true
-	})
+	}))
 
-	N1 := 0
-	for _, count := range operators {
-		N1 += count
-	}
+}
 
-	N2 := 0
-	for _, count := range operands {
-		N2 += count
+// countIdent reports an identifier as an operator or operand. Identifiers
+// that type checking did not resolve are operands.
+func countIdent(info *types.Info, id *ast.Ident, operator func(token.Pos, string), operand func(token.Pos, string)) {
+	if info == nil {
+		operand(id.Pos(), id.Name)
+		return
 	}
 
-	Eta1 := len(operators)
-	Eta2 := len(operands)
-
-	length := N1 + N2
-	vocabulary := Eta1 + Eta2
-
-	// Halstead calculations (with adjustments)
-	var volume, difficulty, effort float64
+	switch obj := info.ObjectOf(id).(type) {
+	case *types.PkgName:
+		// Counted with the selected name
+	case *types.TypeName, *types.Builtin:
+		operator(id.Pos(), objectName(obj))
+	case *types.Func:
+		// A declared function's name is counted by its declaration
+		if info.Defs[id] == nil {
+			operator(id.Pos(), obj.FullName())
+		}
+	case *types.Var:
+		operand(id.Pos(), fmt.Sprintf("%s#%d", obj.Name(), obj.Pos()))
+	default:
+		operand(id.Pos(), id.Name)
+	}
+}
 
-	if vocabulary > 0 {
-		volume = float64(length) * math.Log2(float64(vocabulary))
+// isPackageName reports whether expr names an imported package.
+func isPackageName(info *types.Info, expr ast.Expr) bool {
+	if info == nil {
+		return false
 	}
-	if Eta2 > 0 {
-		difficulty = (float64(Eta1) / 2.0) * (float64(N2) / float64(Eta2))
+	id, ok := expr.(*ast.Ident)
+	if !ok {
+		return false
 	}
-	effort = difficulty * volume
+	_, ok = info.Uses[id].(*types.PkgName)
+	return ok
+}
 
-	metrics := HalsteadMetrics{
-		N1:         N1,
-		N2:         N2,
-		Eta1:       Eta1,
-		Eta2:       Eta2,
-		Length:     length,
-		Vocabulary: vocabulary,
-		Volume:     volume,
-		Difficulty: difficulty,
-		Effort:     effort,
+// objectName returns the name of obj qualified by its package path.
+func objectName(obj types.Object) string {
+	if obj.Pkg() == nil {
+		return obj.Name()
 	}
-
-	return metrics, nil
+	return obj.Pkg().Path() + "." + obj.Name()
 }
@@ -0,0 +1,186 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Function kinds reported in MetricsResult.Kind.
+const (
+	KindFunc    = "func"
+	KindMethod  = "method"
+	KindClosure = "closure"
+)
+
+// SetPackagePath sets the import path used to qualify function names. It
+// defaults to the package name declared in the file.
+func (fa *FileAnalyzer) SetPackagePath(pkgPath string) {
+	fa.pkgPath = pkgPath
+}
+
+// PackagePath returns the import path used to qualify function names. Files
+// of an external test package get the path of the package under test with
+// a _test suffix, as the go tool names them.
+func (fa *FileAnalyzer) PackagePath() string {
+	if fa.pkgPath != "" {
+		if fa.ast != nil && strings.HasSuffix(fa.ast.Name.Name, "_test") && !strings.HasSuffix(fa.pkgPath, "_test") {
+			return fa.pkgPath + "_test"
+		}
+		return fa.pkgPath
+	}
+	if fa.ast != nil && fa.ast.Name != nil {
+		return fa.ast.Name.Name
+	}
+	return ""
+}
+
+// identity describes how a function is named in the results.
+type identity struct {
+	kind      string
+	receiver  string
+	qualified string
+}
+
+// functionIdentity builds the qualified name of a function declaration:
+// pkg.Func, pkg.Func[T], pkg.Type.Method or pkg.(*Type[T]).Method.
+func (fa *FileAnalyzer) functionIdentity(funcDecl *ast.FuncDecl) identity {
+	name := funcDecl.Name.Name
+	if funcDecl.Recv == nil && name == "init" {
+		name = fmt.Sprintf("init.%d", fa.initIndex(funcDecl))
+	}
+
+	id := identity{kind: KindFunc}
+	if funcDecl.Recv != nil && len(funcDecl.Recv.List) > 0 {
+		id.kind = KindMethod
+		id.receiver = types.ExprString(funcDecl.Recv.List[0].Type)
+		name = qualifyMethod(id.receiver, name)
+	} else if funcDecl.Type.TypeParams != nil {
+		var params []string
+		for _, field := range funcDecl.Type.TypeParams.List {
+			for _, ident := range field.Names {
+				params = append(params, ident.Name)
+			}
+		}
+		name += "[" + strings.Join(params, ", ") + "]"
+	}
+
+	id.qualified = name
+	if pkgPath := fa.PackagePath(); pkgPath != "" {
+		id.qualified = pkgPath + "." + name
+	}
+	return id
+}
+
+// NumberInits numbers the init functions of files, and the function literals
+// of their package-level initializers, across each package in file name
+// order, as the compiler does. Otherwise they are numbered within their
+// file, and the files of a package repeat the same names. Files are grouped
+// like LoadPackages does, so SetPackagePath should be called first.
+func NumberInits(files []*FileAnalyzer) {
+	packages := make(map[string][]*FileAnalyzer)
+	for _, fa := range files {
+		packages[packageKey(fa)] = append(packages[packageKey(fa)], fa)
+	}
+	for _, files := range packages {
+		sort.Slice(files, func(i, j int) bool { return files[i].Filename() < files[j].Filename() })
+		inits, initLits := 0, 0
+		for _, fa := range files {
+			fa.initBase, fa.initLitBase = inits, initLits
+			for _, decl := range fa.ast.Decls {
+				switch decl := decl.(type) {
+				case *ast.FuncDecl:
+					if decl.Recv == nil && decl.Name.Name == "init" {
+						inits++
+					}
+				case *ast.GenDecl:
+					initLits += len(childFuncLits(decl))
+				}
+			}
+		}
+	}
+}
+
+// initIndex numbers init functions in source order, as the compiler does,
+// following those of the files numbered before by NumberInits.
+func (fa *FileAnalyzer) initIndex(funcDecl *ast.FuncDecl) int {
+	index := fa.initBase
+	for _, decl := range fa.ast.Decls {
+		if decl == funcDecl {
+			break
+		}
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv == nil && fd.Name.Name == "init" {
+			index++
+		}
+	}
+	return index
+}
+
+// qualifyMethod joins a receiver type and method name, wrapping pointer
+// receivers in parentheses.
+func qualifyMethod(receiver, method string) string {
+	if strings.HasPrefix(receiver, "*") {
+		return "(" + receiver + ")." + method
+	}
+	return receiver + "." + method
+}
+
+// DisplayName returns the function name qualified by its receiver but not by
+// its package, e.g. (*Server).Close.
+func (r *MetricsResult) DisplayName() string {
+	if r.Receiver == "" {
+		return r.Name
+	}
+	return qualifyMethod(r.Receiver, r.Name)
+}
+
+// ImportPath returns the import path of the package in dir, derived from the
+// nearest go.mod above it. It returns an empty string outside a module.
+func ImportPath(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	var rel []string
+	for {
+		if module := readModulePath(filepath.Join(dir, "go.mod")); module != "" {
+			elems := append([]string{module}, rel...)
+			return path.Join(elems...)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		rel = append([]string{filepath.Base(dir)}, rel...)
+		dir = parent
+	}
+}
+
+// readModulePath returns the module path declared in a go.mod file.
+func readModulePath(gomod string) string {
+	content, err := os.ReadFile(gomod)
+	if err != nil {
+		return ""
+	}
+	return parseModulePath(content)
+}
+
+// parseModulePath returns the module path declared in go.mod content.
+func parseModulePath(content []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package analyzer
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestInitNumbering(t *testing.T) {
+	sources := []Source{
+		{Path: "go.mod", Content: []byte("module example\n")},
+		{Path: "b.go", Content: []byte("package example\n\nvar b = func() int { return 2 }()\n\nfunc init() { _ = func() {} }\n")},
+		{Path: "a.go", Content: []byte("package example\n\nvar a = func() int { return 1 }()\n\nfunc init() {}\n")},
+		{Path: "a_test.go", Content: []byte("package example_test\n\nfunc init() {}\n")},
+		{Path: "sub/c.go", Content: []byte("package sub\n\nfunc init() {}\n")},
+	}
+	want := []string{
+		"example.init.0",
+		"example.init.1",
+		"example.init.1.func1",
+		"example.init.func1",
+		"example.init.func2",
+		"example/sub.init.0",
+		"example_test.init.0",
+	}
+	for _, typeCheck := range []bool{false, true} {
+		report, err := AnalyzeSourcesContext(context.Background(), sources, Options{TypeCheck: typeCheck}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, result := range report.Functions() {
+			got = append(got, result.QualifiedName)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("type check %v: names = %v, want %v", typeCheck, got, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// LineMetrics holds the complexity contributions attributed to a single
+// source line.
+type LineMetrics struct {
+	Line         int `json:"line"`
+	BranchPoints int `json:"branchPoints"` // Decision points: if, for, case, select case, && and ||.
+	Nesting      int `json:"nesting"`      // Number of control structures enclosing the line.
+	Cognitive    int `json:"cognitive"`    // Cognitive complexity increments, including nesting bonuses.
+	Operators    int `json:"operators"`    // Halstead operators.
+	Operands     int `json:"operands"`     // Halstead operands.
+}
+
+// AnalyzeLines returns the metrics of every line of the file, in order.
+// Closures are attributed to the lines they appear on, whether or not they
+// are folded into their parent.
+func (fa *FileAnalyzer) AnalyzeLines() []LineMetrics {
+	file := fa.fset.File(fa.ast.Pos())
+	if file == nil {
+		return nil
+	}
+
+	lines := make([]LineMetrics, file.LineCount())
+	for i := range lines {
+		lines[i].Line = i + 1
+	}
+	at := func(pos token.Pos) *LineMetrics {
+		line := fa.fset.Position(pos).Line
+		if line < 1 || line > len(lines) {
+			return nil
+		}
+		return &lines[line-1]
+	}
+
+	// Attribute every unit as if closures were folded, so nested literals
+	// carry the nesting of their surroundings and are counted once.
+	folded := *fa
+	folded.foldClosures = true
+	var units []ast.Node
+	for _, decl := range fa.ast.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			units = append(units, decl)
+		case *ast.GenDecl:
+			for _, lit := range childFuncLits(decl) {
+				units = append(units, lit)
+			}
+		}
+	}
+
+	for _, unit := range units {
+		for _, increment := range folded.ExplainCognitiveComplexity(unit) {
+			if increment.Line >= 1 && increment.Line <= len(lines) {
+				lines[increment.Line-1].Cognitive += increment.Base + increment.Nesting
+			}
+		}
+
+		countHalstead(unit, folded.visitor, folded.info,
+			func(pos token.Pos, _ string) {
+				if l := at(pos); l != nil {
+					l.Operators++
+				}
+			},
+			func(pos token.Pos, _ string) {
+				if l := at(pos); l != nil {
+					l.Operands++
+				}
+			})
+	}
+
+	ast.Inspect(fa.ast, func(n ast.Node) bool {
+		var body *ast.BlockStmt
+		switch n := n.(type) {
+		case *ast.IfStmt:
+			at(n.If).BranchPoints++
+			body = n.Body
+			if block, ok := n.Else.(*ast.BlockStmt); ok {
+				fa.nestLines(lines, block)
+			}
+		case *ast.ForStmt:
+			at(n.For).BranchPoints++
+			body = n.Body
+		case *ast.RangeStmt:
+			at(n.For).BranchPoints++
+			body = n.Body
+		case *ast.SwitchStmt:
+			body = n.Body
+		case *ast.TypeSwitchStmt:
+			body = n.Body
+		case *ast.SelectStmt:
+			body = n.Body
+		case *ast.CaseClause:
+			at(n.Case).BranchPoints += len(n.List)
+		case *ast.CommClause:
+			if n.Comm != nil {
+				at(n.Case).BranchPoints++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				at(n.OpPos).BranchPoints++
+			}
+		}
+		if body != nil {
+			fa.nestLines(lines, body)
+		}
+		return true
+	})
+
+	return lines
+}
+
+// nestLines increments the nesting level of the lines strictly inside body.
+func (fa *FileAnalyzer) nestLines(lines []LineMetrics, body *ast.BlockStmt) {
+	start := fa.fset.Position(body.Lbrace).Line
+	end := fa.fset.Position(body.Rbrace).Line
+	for line := start + 1; line < end && line <= len(lines); line++ {
+		lines[line-1].Nesting++
+	}
+}
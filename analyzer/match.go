@@ -0,0 +1,126 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"io"
+)
+
+// Match pairs a function of an earlier analysis with the same function in a
+// later one. Old is nil for an added function and New for a removed one.
+type Match struct {
+	Old *MetricsResult
+	New *MetricsResult
+}
+
+// MatchFunctions pairs the functions of two analyses of the same code. They
+// are matched by qualified name first; the functions left over on both sides
+// are then matched by fingerprint, which follows unchanged functions across
+// renames and moves. Matches are returned in the order of new, followed by
+// the removed functions in the order of old.
+func MatchFunctions(old, new []*MetricsResult) []Match {
+	matched := make(map[*MetricsResult]*MetricsResult, len(new))
+	oldMatched := make(map[*MetricsResult]bool, len(old))
+
+	byName := make(map[string][]*MetricsResult)
+	for _, result := range old {
+		byName[result.QualifiedName] = append(byName[result.QualifiedName], result)
+	}
+	for _, result := range new {
+		candidates := byName[result.QualifiedName]
+		if len(candidates) == 0 {
+			continue
+		}
+		matched[result] = candidates[0]
+		oldMatched[candidates[0]] = true
+		byName[result.QualifiedName] = candidates[1:]
+	}
+
+	// Only fingerprints unique on both sides identify a function; small
+	// closures in particular often share their body.
+	oldByPrint := uniqueFingerprints(old, func(r *MetricsResult) bool { return !oldMatched[r] })
+	newByPrint := uniqueFingerprints(new, func(r *MetricsResult) bool { return matched[r] == nil })
+	for fp, result := range newByPrint {
+		if previous, ok := oldByPrint[fp]; ok {
+			matched[result] = previous
+			oldMatched[previous] = true
+		}
+	}
+
+	matches := make([]Match, 0, len(new))
+	for _, result := range new {
+		matches = append(matches, Match{Old: matched[result], New: result})
+	}
+	for _, result := range old {
+		if !oldMatched[result] {
+			matches = append(matches, Match{Old: result})
+		}
+	}
+	return matches
+}
+
+// uniqueFingerprints indexes the results selected by include whose
+// fingerprint no other selected result shares.
+func uniqueFingerprints(results []*MetricsResult, include func(*MetricsResult) bool) map[string]*MetricsResult {
+	index := make(map[string]*MetricsResult)
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if result.Fingerprint == "" || !include(result) {
+			continue
+		}
+		if seen[result.Fingerprint] {
+			delete(index, result.Fingerprint)
+			continue
+		}
+		seen[result.Fingerprint] = true
+		index[result.Fingerprint] = result
+	}
+	return index
+}
+
+// fingerprint hashes the structure of the signature and body of a
+// function: node kinds, identifiers, literals and operators. The function's
+// name, comments and formatting do not affect it.
+func (fa *FileAnalyzer) fingerprint(funcType *ast.FuncType, body *ast.BlockStmt) string {
+	h := sha256.New()
+	writeStructure(h, funcType)
+	if body != nil {
+		writeStructure(h, body)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// writeStructure writes a canonical rendering of node to w.
+func writeStructure(w io.Writer, node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case nil:
+			io.WriteString(w, ")")
+			return false
+		case *ast.Ident:
+			fmt.Fprintf(w, "%s", n.Name)
+		case *ast.BasicLit:
+			fmt.Fprintf(w, "%s", n.Value)
+		case *ast.BinaryExpr:
+			fmt.Fprintf(w, "%s", n.Op)
+		case *ast.UnaryExpr:
+			fmt.Fprintf(w, "%s", n.Op)
+		case *ast.AssignStmt:
+			fmt.Fprintf(w, "%s", n.Tok)
+		case *ast.IncDecStmt:
+			fmt.Fprintf(w, "%s", n.Tok)
+		case *ast.BranchStmt:
+			fmt.Fprintf(w, "%s", n.Tok)
+		case *ast.RangeStmt:
+			fmt.Fprintf(w, "%s", n.Tok)
+		case *ast.GenDecl:
+			fmt.Fprintf(w, "%s", n.Tok)
+		case *ast.ChanType:
+			fmt.Fprintf(w, "%d", n.Dir)
+		}
+		fmt.Fprintf(w, "%T(", n)
+		return true
+	})
+}
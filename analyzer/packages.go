@@ -0,0 +1,275 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Package is a set of files type-checked together by LoadPackages.
+type Package struct {
+	Path   string          // Import path, with a _test suffix for external test packages.
+	Name   string          // Package name from the package clause.
+	Files  []*FileAnalyzer // Files of the package, sorted by name.
+	Types  *types.Package
+	Info   *types.Info
+	Errors []error // Type errors; the files are analyzed regardless.
+}
+
+// ParseFile is like NewFileAnalyzer but records positions in fset, which
+// the files given to LoadPackages must share.
+func ParseFile(fset *token.FileSet, filename string, content []byte) (*FileAnalyzer, error) {
+	node, err := parser.ParseFile(fset, filename, content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	fa := &FileAnalyzer{
+		fset:      fset,
+		ast:       node,
+		buildable: matchBuild(filename, content),
+	}
+	fa.class = fa.classify()
+	return fa, nil
+}
+
+// matchBuild reports whether the file is part of its package in the default
+// build context, going by its _GOOS and _GOARCH suffixes and its //go:build
+// constraint.
+func matchBuild(filename string, content []byte) bool {
+	ctxt := build.Default
+	ctxt.OpenFile = func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	}
+	dir, name := filepath.Split(filename)
+	match, err := ctxt.MatchFile(dir, name)
+	return match || err != nil
+}
+
+// LoadPackages type-checks files, parsed by ParseFile with fset, and makes
+// their analyzers use the type information, which classifies Halstead
+// operands accurately and resolves calls. Files are grouped into packages by
+// PackagePath and package name, so SetPackagePath should be called first.
+// Files excluded by their build constraints in the default build context,
+// such as files for another GOOS or tagged ignore, are not type-checked and
+// are analyzed without type information. So are the files importing a
+// package fallback finds no buildable files in, like syscall/js outside
+// js/wasm.
+//
+// Imports of the packages among files are resolved to them. Other imports
+// are type-checked from source by fallback, if not nil; the ones it cannot
+// import resolve to empty packages, and their uses become type errors
+// instead of failing the whole package.
+func LoadPackages(fset *token.FileSet, files []*FileAnalyzer, fallback types.ImporterFrom) []*Package {
+	packages, _ := LoadPackagesContext(context.Background(), fset, files, fallback)
+	return packages
+}
+
+// LoadPackagesContext is like LoadPackages but stops when ctx is done,
+// returning the packages checked so far with the context's error. A package
+// being checked is finished, but its remaining imports resolve to empty
+// packages without calling fallback.
+func LoadPackagesContext(ctx context.Context, fset *token.FileSet, files []*FileAnalyzer, fallback types.ImporterFrom) ([]*Package, error) {
+	l := &loader{
+		ctx:      ctx,
+		fset:     fset,
+		files:    make(map[string][]*FileAnalyzer),
+		packages: make(map[string]*Package),
+		fallback: fallback,
+		fake:     make(map[string]*types.Package),
+		noGo:     make(map[string]bool),
+	}
+	for _, fa := range files {
+		if fa.buildable {
+			l.files[packageKey(fa)] = append(l.files[packageKey(fa)], fa)
+		}
+	}
+	var paths []string
+	for key, files := range l.files {
+		var kept []*FileAnalyzer
+		for _, fa := range files {
+			if !l.importsNoGo(fa) {
+				kept = append(kept, fa)
+			}
+		}
+		if len(kept) == 0 {
+			delete(l.files, key)
+			continue
+		}
+		l.files[key] = kept
+		paths = append(paths, key)
+	}
+	sort.Strings(paths)
+
+	packages := make([]*Package, 0, len(paths))
+	for _, key := range paths {
+		if err := ctx.Err(); err != nil {
+			return packages, err
+		}
+		packages = append(packages, l.load(key))
+	}
+	return packages, ctx.Err()
+}
+
+// StdImporter returns an importer that type-checks the standard library
+// from source and imports nothing else. The importer is shared by the whole
+// process and safe for concurrent use, so that every standard package is
+// checked once; its positions are recorded in a file set of its own.
+func StdImporter() types.ImporterFrom {
+	std.once.Do(func() {
+		std.importer = importer.ForCompiler(token.NewFileSet(), "source", nil).(types.ImporterFrom)
+	})
+	return &std
+}
+
+// std is the importer StdImporter returns.
+var std stdImporter
+
+type stdImporter struct {
+	once     sync.Once
+	mu       sync.Mutex
+	importer types.ImporterFrom
+}
+
+func (s *stdImporter) Import(path string) (*types.Package, error) {
+	return s.ImportFrom(path, "", 0)
+}
+
+func (s *stdImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	// Standard library paths have no dot in their first element
+	if first, _, _ := strings.Cut(path, "/"); strings.Contains(first, ".") {
+		return nil, fmt.Errorf("%s is not in the standard library", path)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.importer.ImportFrom(path, dir, mode)
+}
+
+// loader type-checks packages on demand, so that a package is checked
+// before the packages importing it.
+type loader struct {
+	ctx      context.Context
+	fset     *token.FileSet
+	files    map[string][]*FileAnalyzer
+	packages map[string]*Package // Checked or being checked, by key.
+	fallback types.ImporterFrom
+	fake     map[string]*types.Package
+	noGo     map[string]bool // Imports without buildable files, by path.
+}
+
+// load type-checks the package with the given key.
+func (l *loader) load(key string) *Package {
+	if pkg, ok := l.packages[key]; ok {
+		return pkg
+	}
+
+	files := l.files[key]
+	sort.Slice(files, func(i, j int) bool { return files[i].Filename() < files[j].Filename() })
+	pkg := &Package{
+		Path:  key,
+		Name:  files[0].ast.Name.Name,
+		Files: files,
+		Info: &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		},
+	}
+	l.packages[key] = pkg
+
+	asts := make([]*ast.File, len(files))
+	for i, fa := range files {
+		asts[i] = fa.ast
+	}
+	conf := types.Config{
+		Importer: l,
+		Error:    func(err error) { pkg.Errors = append(pkg.Errors, err) },
+	}
+	// Errors are collected above; Check returns the first of them
+	pkg.Types, _ = conf.Check(strings.TrimSuffix(key, "_test"), l.fset, asts, pkg.Info)
+
+	for _, fa := range files {
+		fa.info = pkg.Info
+	}
+	return pkg
+}
+
+// importsNoGo reports whether fa imports a package, other than those being
+// loaded, that fallback finds but whose files are all excluded by build
+// constraints.
+func (l *loader) importsNoGo(fa *FileAnalyzer) bool {
+	if l.fallback == nil {
+		return false
+	}
+	for _, spec := range fa.ast.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if _, ok := l.files[importPath]; ok || err != nil {
+			continue
+		}
+		noGo, ok := l.noGo[importPath]
+		if !ok && l.ctx.Err() == nil {
+			_, err := l.fallback.ImportFrom(importPath, filepath.Dir(fa.Filename()), 0)
+			var noGoErr *build.NoGoError
+			noGo = errors.As(err, &noGoErr)
+			l.noGo[importPath] = noGo
+		}
+		if noGo {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *loader) Import(path string) (*types.Package, error) {
+	return l.ImportFrom(path, "", 0)
+}
+
+func (l *loader) ImportFrom(importPath, dir string, mode types.ImportMode) (*types.Package, error) {
+	if _, ok := l.files[importPath]; ok {
+		// A package still being checked imports itself through a cycle
+		if pkg := l.load(importPath); pkg.Types != nil {
+			return pkg.Types, nil
+		}
+	} else if l.fallback != nil && l.ctx.Err() == nil {
+		if pkg, err := l.fallback.ImportFrom(importPath, dir, mode); err == nil {
+			return pkg, nil
+		}
+	}
+
+	pkg, ok := l.fake[importPath]
+	if !ok {
+		pkg = types.NewPackage(importPath, path.Base(importPath))
+		pkg.MarkComplete()
+		l.fake[importPath] = pkg
+	}
+	return pkg, nil
+}
+
+// packageKey returns the key LoadPackages groups fa by: its package path,
+// or its directory without one, plus _test for external test packages.
+func packageKey(fa *FileAnalyzer) string {
+	if fa.pkgPath != "" {
+		return fa.PackagePath()
+	}
+	key := filepath.ToSlash(filepath.Dir(fa.Filename()))
+	if strings.HasSuffix(fa.ast.Name.Name, "_test") {
+		key += "_test"
+	}
+	return key
+}
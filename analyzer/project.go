@@ -0,0 +1,365 @@
+package analyzer
+
+import (
+	"context"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Source is a file to analyze, identified by its slash-separated path.
+// Sources other than .go files are ignored, except go.mod files which
+// provide the module path used to name packages.
+type Source struct {
+	Path    string
+	Content []byte
+}
+
+// Options control how sources are analyzed.
+type Options struct {
+	Metrics        []string // Metrics to compute, see FileAnalyzer.SetMetrics.
+	FoldClosures   bool     // See FileAnalyzer.SetFoldClosures.
+	ExcludeClasses []string // Classes of files to leave out of reports, see Classes.
+	TypeCheck      bool     // Type-check packages first, see LoadPackages. Only the standard library is imported.
+}
+
+// Report is the combined result of analyzing a set of sources.
+type Report struct {
+	Summary    *Summary         `json:"summary"`
+	Classes    []*Summary       `json:"classes"` // One summary per file class present.
+	Packages   []*PackageReport `json:"packages"`
+	Excluded   []*FileReport    `json:"excluded,omitempty"`   // Files left out by class, without functions.
+	Violations []Violation      `json:"violations,omitempty"` // Set by callers that check thresholds.
+}
+
+// PackageReport groups the files of one package.
+type PackageReport struct {
+	Path     string          `json:"path"` // Import path, or directory outside a module.
+	Name     string          `json:"name"` // Package name from the package clause.
+	Summary  *Summary        `json:"summary"`
+	Coupling *PackageMetrics `json:"coupling"` // Coupling with the other packages of the report; nil for test packages.
+	Types    []*TypeCohesion `json:"types"`    // Cohesion of the struct types, in declaration order.
+	Files    []*FileReport   `json:"files"`
+}
+
+// FileReport holds the results of one file, or the error that prevented
+// its analysis.
+type FileReport struct {
+	Path           string           `json:"path"`
+	Package        string           `json:"package"`
+	Classification string           `json:"classification"`
+	Excluded       bool             `json:"excluded,omitempty"` // Left out by class; Functions is empty.
+	Error          string           `json:"error,omitempty"`
+	Warnings       []string         `json:"warnings,omitempty"` // Malformed complexity:ignore directives.
+	Functions      []*MetricsResult `json:"functions"`
+
+	pkgName string
+	facts   fileFacts
+}
+
+// Functions returns the functions of every file in the report.
+func (r *Report) Functions() []*MetricsResult {
+	var functions []*MetricsResult
+	for _, pkg := range r.Packages {
+		for _, file := range pkg.Files {
+			functions = append(functions, file.Functions...)
+		}
+	}
+	return functions
+}
+
+// Coupling returns the coupling metrics of every package in the report.
+func (r *Report) Coupling() []*PackageMetrics {
+	metrics := make([]*PackageMetrics, 0, len(r.Packages))
+	for _, pkg := range r.Packages {
+		if pkg.Coupling != nil {
+			metrics = append(metrics, pkg.Coupling)
+		}
+	}
+	return metrics
+}
+
+// Cohesion returns the cohesion metrics of the struct types of every
+// package in the report.
+func (r *Report) Cohesion() []*TypeCohesion {
+	metrics := []*TypeCohesion{}
+	for _, pkg := range r.Packages {
+		metrics = append(metrics, pkg.Types...)
+	}
+	return metrics
+}
+
+// Errors returns the files that could not be analyzed.
+func (r *Report) Errors() []*FileReport {
+	var failed []*FileReport
+	for _, pkg := range r.Packages {
+		for _, file := range pkg.Files {
+			if file.Error != "" {
+				failed = append(failed, file)
+			}
+		}
+	}
+	return failed
+}
+
+// AnalyzeSources analyzes every .go source and groups the results by package
+// and file. A file that fails to parse is reported with its error instead of
+// failing the whole report.
+func AnalyzeSources(sources []Source) *Report {
+	report, _ := AnalyzeSourcesContext(context.Background(), sources, Options{}, nil)
+	return report
+}
+
+// AnalyzeSourcesContext is like AnalyzeSources but applies options and stops
+// when ctx is done, returning the report of the files analyzed so far
+// together with the context's error. progress, if not nil, is called after
+// each file, including files excluded by class.
+func AnalyzeSourcesContext(ctx context.Context, sources []Source, options Options, progress func(*FileReport)) (*Report, error) {
+	modules := moduleRoots(sources)
+	if options.TypeCheck {
+		return analyzeCheckedSources(ctx, sources, modules, options, progress)
+	}
+
+	// Every file is parsed first, so that init functions are numbered
+	// across their package
+	var files []*FileReport
+	var analyzers []*FileAnalyzer
+	for _, source := range sources {
+		if err := ctx.Err(); err != nil {
+			return NewReport(nil), err
+		}
+		if path.Ext(source.Path) != ".go" {
+			continue
+		}
+		file, fileAnalyzer := parseSource(token.NewFileSet(), source, modules, options)
+		files = append(files, file)
+		analyzers = append(analyzers, fileAnalyzer)
+	}
+	NumberInits(parsedAnalyzers(analyzers))
+
+	for i, file := range files {
+		if err := analyzeParsed(ctx, file, analyzers[i], options); err != nil {
+			return NewReport(files[:i]), err
+		}
+		// The analyzer is not needed anymore
+		analyzers[i] = nil
+		if progress != nil {
+			progress(file)
+		}
+	}
+
+	return NewReport(files), nil
+}
+
+// analyzeCheckedSources is AnalyzeSourcesContext with type checking: every
+// source is parsed before the packages are checked and analyzed, and the
+// calls between the analyzed functions are linked.
+func analyzeCheckedSources(ctx context.Context, sources []Source, modules map[string]string, options Options, progress func(*FileReport)) (*Report, error) {
+	fset := token.NewFileSet()
+	var files []*FileReport
+	var analyzers []*FileAnalyzer
+	for _, source := range sources {
+		if err := ctx.Err(); err != nil {
+			return NewReport(nil), err
+		}
+		if path.Ext(source.Path) != ".go" {
+			continue
+		}
+		file, fileAnalyzer := parseSource(fset, source, modules, options)
+		files = append(files, file)
+		analyzers = append(analyzers, fileAnalyzer)
+	}
+
+	checked := parsedAnalyzers(analyzers)
+	NumberInits(checked)
+	if _, err := LoadPackagesContext(ctx, fset, checked, StdImporter()); err != nil {
+		return NewReport(nil), err
+	}
+
+	// Fan-in needs every caller, so progress is reported once all files
+	// are analyzed and linked
+	var results []*MetricsResult
+	for i, file := range files {
+		if err := analyzeParsed(ctx, file, analyzers[i], options); err != nil {
+			return NewReport(files[:i]), err
+		}
+		results = append(results, file.Functions...)
+	}
+	LinkCalls(results)
+
+	if progress != nil {
+		for _, file := range files {
+			progress(file)
+		}
+	}
+	return NewReport(files), nil
+}
+
+// parsedAnalyzers returns the analyzers parseSource did not leave nil.
+func parsedAnalyzers(analyzers []*FileAnalyzer) []*FileAnalyzer {
+	var parsed []*FileAnalyzer
+	for _, fileAnalyzer := range analyzers {
+		if fileAnalyzer != nil {
+			parsed = append(parsed, fileAnalyzer)
+		}
+	}
+	return parsed
+}
+
+// CountGoSources returns the number of sources AnalyzeSources analyzes.
+func CountGoSources(sources []Source) int {
+	count := 0
+	for _, source := range sources {
+		if path.Ext(source.Path) == ".go" {
+			count++
+		}
+	}
+	return count
+}
+
+// parseSource parses one .go source of a set whose go.mod files are
+// described by modules. The analyzer is nil if the file failed to parse or
+// its class is excluded, which the report records.
+func parseSource(fset *token.FileSet, source Source, modules map[string]string, options Options) (*FileReport, *FileAnalyzer) {
+	file := &FileReport{
+		Path:           source.Path,
+		Package:        sourceImportPath(path.Dir(source.Path), modules),
+		Classification: ClassifyPath(source.Path),
+		Functions:      []*MetricsResult{},
+	}
+
+	// Excluded vendored and testdata files are not even parsed; testdata in
+	// particular often holds invalid Go on purpose.
+	if ContainsClass(options.ExcludeClasses, file.Classification) {
+		file.Excluded = true
+		return file, nil
+	}
+
+	fileAnalyzer, err := ParseFile(fset, source.Path, source.Content)
+	if err != nil {
+		file.Error = err.Error()
+		return file, nil
+	}
+
+	file.Classification = fileAnalyzer.Classification()
+	file.pkgName = fileAnalyzer.ast.Name.Name
+	if file.Package == "." {
+		file.Package = file.pkgName
+	}
+	if ContainsClass(options.ExcludeClasses, file.Classification) {
+		file.Excluded = true
+		return file, nil
+	}
+
+	fileAnalyzer.SetPackagePath(file.Package)
+	file.Package = fileAnalyzer.PackagePath()
+	return file, fileAnalyzer
+}
+
+// analyzeParsed records the results of a file parsed by parseSource. It
+// returns the context's error, leaving file incomplete, if ctx is done
+// before the file is analyzed.
+func analyzeParsed(ctx context.Context, file *FileReport, fileAnalyzer *FileAnalyzer, options Options) error {
+	if fileAnalyzer == nil {
+		return ctx.Err()
+	}
+	fileAnalyzer.SetMetrics(options.Metrics)
+	fileAnalyzer.SetFoldClosures(options.FoldClosures)
+	results, err := fileAnalyzer.AnalyzeFileContext(ctx)
+	if err != nil {
+		return err
+	}
+	if results != nil {
+		file.Functions = results
+	}
+	for _, err := range fileAnalyzer.DirectiveErrors() {
+		file.Warnings = append(file.Warnings, err.Error())
+	}
+	file.facts = fileAnalyzer.facts()
+	return nil
+}
+
+// NewReport groups file reports by package, summarizes them and computes
+// the coupling between the packages and the cohesion of their types.
+// Excluded files are listed apart.
+func NewReport(files []*FileReport) *Report {
+	packages := make(map[string]*PackageReport)
+	var all []*MetricsResult
+	var excluded []*FileReport
+	for _, file := range files {
+		if file.Excluded {
+			excluded = append(excluded, file)
+			continue
+		}
+		pkg, ok := packages[file.Package]
+		if !ok {
+			pkg = &PackageReport{Path: file.Package}
+			packages[file.Package] = pkg
+		}
+		if pkg.Name == "" {
+			pkg.Name = file.pkgName
+		}
+		pkg.Files = append(pkg.Files, file)
+		all = append(all, file.Functions...)
+	}
+
+	report := &Report{
+		Summary:  Summarize("", all),
+		Classes:  SummarizeClasses(all),
+		Packages: make([]*PackageReport, 0, len(packages)),
+		Excluded: excluded,
+	}
+	facts := make(map[string][]fileFacts, len(packages))
+	for _, pkg := range packages {
+		sort.Slice(pkg.Files, func(i, j int) bool { return pkg.Files[i].Path < pkg.Files[j].Path })
+		var functions []*MetricsResult
+		for _, file := range pkg.Files {
+			functions = append(functions, file.Functions...)
+			facts[pkg.Path] = append(facts[pkg.Path], file.facts)
+		}
+		pkg.Summary = Summarize(pkg.Path, functions)
+		pkg.Types = cohesion(pkg.Path, facts[pkg.Path])
+		report.Packages = append(report.Packages, pkg)
+	}
+	for _, metrics := range coupling(facts) {
+		packages[metrics.Package].Coupling = metrics
+	}
+	sort.Slice(report.Packages, func(i, j int) bool { return report.Packages[i].Path < report.Packages[j].Path })
+
+	return report
+}
+
+// moduleRoots maps the directory of every go.mod source to its module path.
+func moduleRoots(sources []Source) map[string]string {
+	modules := make(map[string]string)
+	for _, source := range sources {
+		if path.Base(source.Path) != "go.mod" {
+			continue
+		}
+		if module := parseModulePath(source.Content); module != "" {
+			modules[path.Dir(source.Path)] = module
+		}
+	}
+	return modules
+}
+
+// sourceImportPath returns the import path of the package in dir, using the
+// innermost module containing it, or dir itself outside any module.
+func sourceImportPath(dir string, modules map[string]string) string {
+	for d := dir; ; d = path.Dir(d) {
+		if module, ok := modules[d]; ok {
+			if d == dir {
+				return module
+			}
+			rel := strings.TrimPrefix(dir, d+"/")
+			if d == "." {
+				rel = dir
+			}
+			return path.Join(module, rel)
+		}
+		if d == "." || d == "/" {
+			return dir
+		}
+	}
+}
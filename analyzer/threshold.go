@@ -0,0 +1,148 @@
+package analyzer
+
+import "fmt"
+
+// Metric names used by thresholds, violations and reports.
+const (
+	MetricCyclomatic      = "cyclomatic"
+	MetricCognitive       = "cognitive"
+	MetricNesting         = "nesting"
+	MetricParameters      = "parameters"
+	MetricMaintainability = "maintainability"
+)
+
+// Names of the metrics that have no threshold, used to select the metrics
+// to compute.
+const (
+	MetricNPath    = "npath"
+	MetricHalstead = "halstead"
+	MetricLines    = "lines"
+	MetricComments = "comments"
+	MetricReturns  = "returns"
+	MetricCalls    = "calls" // Interface calls, fan-in, fan-out and information flow; requires type information.
+)
+
+// Metrics lists every metric name.
+var Metrics = []string{
+	MetricCyclomatic, MetricNPath, MetricCognitive, MetricHalstead, MetricMaintainability,
+	MetricLines, MetricNesting, MetricComments, MetricParameters, MetricReturns, MetricCalls,
+}
+
+// Thresholds holds the limits a function must stay within. A zero or
+// negative value disables the corresponding check.
+type Thresholds struct {
+	MaxCyclomatic      int     `json:"maxCyclomatic,omitempty" yaml:"maxCyclomatic"`
+	MaxCognitive       int     `json:"maxCognitive,omitempty" yaml:"maxCognitive"`
+	MaxNestedDepth     int     `json:"maxNestedDepth,omitempty" yaml:"maxNestedDepth"`
+	MaxParameters      int     `json:"maxParameters,omitempty" yaml:"maxParameters"`
+	MinMaintainability float64 `json:"minMaintainability,omitempty" yaml:"minMaintainability"`
+}
+
+// Violation describes a function exceeding one of the thresholds.
+type Violation struct {
+	File          string  `json:"file"`
+	Line          int     `json:"line"`
+	Function      string  `json:"function"`
+	QualifiedName string  `json:"qualifiedName"`
+	Metric        string  `json:"metric"`
+	Value         float64 `json:"value"`
+	Limit         float64 `json:"limit"`
+	Suppressed    bool    `json:"suppressed,omitempty"`   // Accepted by a directive or the configuration.
+	SuppressedBy  string  `json:"suppressedBy,omitempty"` // SuppressedByDirective or SuppressedByConfig.
+	Reason        string  `json:"reason,omitempty"`       // Why the violation is accepted.
+}
+
+// String formats the violation as file:line: message.
+func (v Violation) String() string {
+	return fmt.Sprintf("%s:%d: %s", v.File, v.Line, v.Message())
+}
+
+// Message describes the violation without its location.
+func (v Violation) Message() string {
+	if v.Metric == MetricMaintainability {
+		return fmt.Sprintf("%s: %s %.2f is below %.2f", v.Function, v.Metric, v.Value, v.Limit)
+	}
+	return fmt.Sprintf("%s: %s %g exceeds %g", v.Function, v.Metric, v.Value, v.Limit)
+}
+
+// Enabled reports whether any limit is set.
+func (t Thresholds) Enabled() bool {
+	return t.MaxCyclomatic > 0 || t.MaxCognitive > 0 || t.MaxNestedDepth > 0 ||
+		t.MaxParameters > 0 || t.MinMaintainability > 0
+}
+
+// Suppress marks the violation as accepted.
+func (v *Violation) Suppress(by, reason string) {
+	v.Suppressed = true
+	v.SuppressedBy = by
+	v.Reason = reason
+}
+
+// SplitSuppressed separates the violations that fail a check from the
+// suppressed ones, keeping their order.
+func SplitSuppressed(violations []Violation) (active, suppressed []Violation) {
+	for _, v := range violations {
+		if v.Suppressed {
+			suppressed = append(suppressed, v)
+		} else {
+			active = append(active, v)
+		}
+	}
+	return active, suppressed
+}
+
+// Check returns the violations of every result, in the order of results.
+func (t Thresholds) Check(results []*MetricsResult) []Violation {
+	var violations []Violation
+	for _, result := range results {
+		violations = append(violations, t.CheckFunction(result)...)
+	}
+	return violations
+}
+
+// CheckFunction returns the violations of a single function. Violations of
+// metrics covered by one of the function's suppressions are marked
+// suppressed.
+func (t Thresholds) CheckFunction(result *MetricsResult) []Violation {
+	if result == nil {
+		return nil
+	}
+
+	var violations []Violation
+	add := func(metric string, value, limit float64) {
+		v := Violation{
+			File:          result.File,
+			Line:          result.Line,
+			Function:      result.DisplayName(),
+			QualifiedName: result.QualifiedName,
+			Metric:        metric,
+			Value:         value,
+			Limit:         limit,
+		}
+		for _, s := range result.Suppressions {
+			if s.Covers(metric) {
+				v.Suppress(SuppressedByDirective, s.Reason)
+				break
+			}
+		}
+		violations = append(violations, v)
+	}
+
+	if t.MaxCyclomatic > 0 && result.CyclomaticComplexity > t.MaxCyclomatic {
+		add(MetricCyclomatic, float64(result.CyclomaticComplexity), float64(t.MaxCyclomatic))
+	}
+	if t.MaxCognitive > 0 && result.CognitiveComplexity > t.MaxCognitive {
+		add(MetricCognitive, float64(result.CognitiveComplexity), float64(t.MaxCognitive))
+	}
+	if t.MaxNestedDepth > 0 && result.NestedDepth > t.MaxNestedDepth {
+		add(MetricNesting, float64(result.NestedDepth), float64(t.MaxNestedDepth))
+	}
+	if t.MaxParameters > 0 && result.FunctionParameters > t.MaxParameters {
+		add(MetricParameters, float64(result.FunctionParameters), float64(t.MaxParameters))
+	}
+	if t.MinMaintainability > 0 && result.MaintainabilityIndex < t.MinMaintainability {
+		add(MetricMaintainability, result.MaintainabilityIndex, t.MinMaintainability)
+	}
+
+	return violations
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+	"github.com/gin-gonic/gin"
+)
+
+// handleCallGraph answers with the call graph of the uploaded files as
+// nodes and edges. The files are type-checked whatever the configuration
+// says, since calls cannot be resolved otherwise.
+func handleCallGraph(c *gin.Context) {
+	sources, ok := readSources(c, int64(cfg.Server.MaxUploadSize))
+	if !ok {
+		return
+	}
+
+	options := cfg.Options()
+	options.TypeCheck = true
+	if len(options.Metrics) > 0 {
+		options.Metrics = append(options.Metrics[:len(options.Metrics):len(options.Metrics)], analyzer.MetricCalls)
+	}
+
+	// Type-checking the imported standard library packages can outlast the
+	// server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Error clearing write deadline: %v", err)
+	}
+
+	report, err := analyzer.AnalyzeSourcesContext(c.Request.Context(), sources, options, nil)
+	if err != nil {
+		return
+	}
+	functions := report.Functions()
+	if len(functions) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "No functions found in uploaded files",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, analyzer.LinkCalls(functions))
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+	"github.com/aman/code-complexity-viz/config"
+	"github.com/aman/code-complexity-viz/report"
+	"github.com/aman/code-complexity-viz/vcs"
+)
+
+// runDiff compares the functions of the .go files changed between the
+// revisions of spec in the git repository containing the current directory:
+// "base..head", "base...head" for the changes of head since its merge base
+// with base, or "base" for base..HEAD. Limits apply to the new version of
+// every added or modified function. It returns the exit status.
+func runDiff(spec, format string, cfg *config.Config) int {
+	base, head, mergeBase := spec, "", false
+	if b, h, ok := strings.Cut(spec, "..."); ok {
+		base, head, mergeBase = b, h, true
+	} else if b, h, ok := strings.Cut(spec, ".."); ok {
+		base, head = b, h
+	}
+	if head == "" {
+		head = "HEAD"
+	}
+
+	repo, err := vcs.Open(".")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "complexity: %v\n", err)
+		return 2
+	}
+	deltas, failed, err := diffRevisions(repo, base, head, mergeBase, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "complexity: %v\n", err)
+		return 2
+	}
+
+	var changed []*analyzer.MetricsResult
+	for _, delta := range deltas {
+		if delta.New != nil {
+			changed = append(changed, delta.New)
+		}
+	}
+	violations, suppressed := analyzer.SplitSuppressed(cfg.Check(changed))
+
+	switch format {
+	case "table":
+		printDiffTable(os.Stdout, deltas)
+	case "json":
+		err = writeJSON(os.Stdout, deltas)
+	case "sarif":
+		err = writeJSON(os.Stdout, report.SARIF(".", changed, append(violations, suppressed...)))
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "complexity: %v\n", err)
+		return 2
+	}
+
+	for _, v := range violations {
+		fmt.Fprintln(os.Stderr, v)
+	}
+	if failed {
+		return 2
+	}
+	if len(violations) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// diffRevisions analyzes the changed .go files the configuration includes at
+// both revisions and compares their functions. With mergeBase, base is
+// replaced by the merge base of base and head. Files are named relative to
+// the current directory, as outside diffs, so that the configuration
+// matches them the same way. Files that fail to parse are reported on
+// stderr; failed reports whether any did.
+func diffRevisions(repo *vcs.Repo, base, head string, mergeBase bool, cfg *config.Config) (deltas []analyzer.Delta, failed bool, err error) {
+	for _, rev := range []*string{&base, &head} {
+		if *rev, err = repo.ResolveRevision(*rev); err != nil {
+			return nil, false, err
+		}
+	}
+	if mergeBase {
+		if base, err = repo.MergeBase(base, head); err != nil {
+			return nil, false, err
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, false, err
+	}
+
+	changes, err := repo.ChangedGoFiles(base, head)
+	if err != nil {
+		return nil, false, err
+	}
+	var basePaths, headPaths []string
+	for _, change := range changes {
+		if !cfg.Includes(filepath.Join(repo.Root(), change.Path)) {
+			continue
+		}
+		if change.Status != vcs.Added {
+			basePaths = append(basePaths, change.Path)
+		}
+		if change.Status != vcs.Deleted {
+			headPaths = append(headPaths, change.Path)
+		}
+	}
+
+	var results [2][]*analyzer.MetricsResult
+	for i, rev := range []struct {
+		name  string
+		paths []string
+	}{{base, basePaths}, {head, headPaths}} {
+		sources, err := repo.Sources(rev.name, rev.paths)
+		if err != nil {
+			return nil, false, err
+		}
+		for i := range sources {
+			sources[i].Path = localPath(repo.Root(), wd, sources[i].Path)
+		}
+		analysis, err := analyzer.AnalyzeSourcesContext(context.Background(), sources, cfg.Options(), nil)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, file := range analysis.Errors() {
+			fmt.Fprintf(os.Stderr, "complexity: %s: %s\n", shortRevision(rev.name), file.Error)
+			failed = true
+		}
+		// The directives of head are the ones that apply
+		for _, pkg := range analysis.Packages {
+			for _, file := range pkg.Files {
+				for _, warning := range file.Warnings {
+					if i == 1 {
+						fmt.Fprintf(os.Stderr, "complexity: warning: %s\n", warning)
+					}
+				}
+			}
+		}
+		results[i] = analysis.Functions()
+	}
+
+	return analyzer.Compare(results[0], results[1]), failed, nil
+}
+
+// localPath converts the slash path p, relative to the repository root,
+// into a slash path relative to the working directory wd.
+func localPath(root, wd, p string) string {
+	rel, err := filepath.Rel(wd, filepath.Join(root, filepath.FromSlash(p)))
+	if err != nil {
+		return p
+	}
+	return filepath.ToSlash(rel)
+}
+
+// printDiffTable writes one row per changed function with its new metrics
+// and their change. Removed functions show their last values.
+func printDiffTable(out io.Writer, deltas []analyzer.Delta) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tLOCATION\tFUNCTION\tCYCLO\tCOGN\tMI\tLOC\tNEST")
+	for _, d := range deltas {
+		fn := d.New
+		if fn == nil {
+			fn = d.Old
+		}
+		name := fn.DisplayName()
+		if d.OldName != "" {
+			name += " (was " + d.Old.DisplayName() + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s:%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			d.Status, fn.File, fn.Line, name,
+			withDelta(fmt.Sprint(fn.CyclomaticComplexity), d.Cyclomatic, d.Status),
+			withDelta(fmt.Sprint(fn.CognitiveComplexity), d.Cognitive, d.Status),
+			withDelta(fmt.Sprintf("%.0f", fn.MaintainabilityIndex), int(math.Round(d.Maintainability)), d.Status),
+			withDelta(fmt.Sprint(fn.LinesOfCode), d.LinesOfCode, d.Status),
+			withDelta(fmt.Sprint(fn.NestedDepth), d.NestedDepth, d.Status))
+	}
+	w.Flush()
+}
+
+// withDelta renders a value of a modified function followed by its change.
+func withDelta(value string, delta int, status string) string {
+	if status != analyzer.DeltaModified || delta == 0 {
+		return value
+	}
+	return fmt.Sprintf("%s (%+d)", value, delta)
+}
+
+func shortRevision(rev string) string {
+	if len(rev) > 12 {
+		return rev[:12]
+	}
+	return rev
+}
@@ -0,0 +1,516 @@
+// Command complexity analyzes Go files, directories and packages from the
+// command line and prints the complexity metrics of every function.
+//
+// Usage:
+//
+//	complexity [flags] [packages, directories or files]
+//
+// Patterns follow the go tool: "./..." walks the current directory
+// recursively, a directory analyzes the .go files it contains and a file
+// is analyzed on its own. Without arguments the current directory is used.
+//
+// When any of the -max-* or -min-* limits is set, every function exceeding
+// a limit is reported on stderr as file:line and the command exits with
+// status 1. Status 2 means the analysis itself failed.
+//
+// Settings are read from the configuration file named by -config, or from
+// the .complexity.yaml file found in the current directory or its parents;
+// see package config. Flags given on the command line take precedence.
+//
+// Run complexity -help for the other modes, such as -diff, -baseline,
+// -coupling, -cohesion and -types; the README describes them in detail.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+	"github.com/aman/code-complexity-viz/config"
+	"github.com/aman/code-complexity-viz/report"
+	"github.com/aman/code-complexity-viz/store"
+)
+
+// fileResult holds the metrics of every function in a single file.
+type fileResult struct {
+	File      string                    `json:"file"`
+	Functions []*analyzer.MetricsResult `json:"functions"`
+}
+
+func main() {
+	configPath := flag.String("config", "", "configuration file (default: "+config.FileName+" in the current directory or a parent)")
+	format := flag.String("format", "table", "output format: table, json or sarif")
+	summary := flag.String("summary", "", "aggregate the results per \"file\", \"package\" or \"class\" instead of listing functions")
+	coupling := flag.Bool("coupling", false, "list the coupling metrics of every package instead of functions")
+	cohesion := flag.Bool("cohesion", false, "list the cohesion metrics of every struct type instead of functions")
+	explain := flag.Bool("explain", false, "list the increments behind each function's cognitive complexity")
+	foldClosures := flag.Bool("fold-closures", false, "count function literals towards the enclosing function as well")
+	typeCheck := flag.Bool("types", false, "type-check packages first, classifying Halstead operands by type and counting interface calls")
+	exclude := flag.String("exclude", "", "comma-separated file classes to leave out: source, test, generated, vendor, testdata (default: vendor,testdata,generated)")
+	baselinePath := flag.String("baseline", "", "accept the violations recorded in this baseline file")
+	writeBaseline := flag.String("write-baseline", "", "record the current violations in this baseline file and exit")
+	tighten := flag.Bool("tighten", false, "with -baseline, lower the baseline to the current values of improved entries")
+	diff := flag.String("diff", "", "compare the functions changed between two git revisions, \"base..head\", \"base...head\" from their merge base, or \"base\" for base..HEAD")
+	storeDir := flag.String("store", "", "record the results as a run in this directory")
+	project := flag.String("project", "", "project name of the recorded run (default: the module path of the current directory)")
+	revision := flag.String("revision", "", "revision label of the recorded run, such as a commit hash")
+	var thresholds analyzer.Thresholds
+	flag.IntVar(&thresholds.MaxCyclomatic, "max-cyclomatic", 0, "maximum cyclomatic complexity per function (0 disables)")
+	flag.IntVar(&thresholds.MaxCognitive, "max-cognitive", 0, "maximum cognitive complexity per function (0 disables)")
+	flag.IntVar(&thresholds.MaxNestedDepth, "max-nesting", 0, "maximum nested depth per function (0 disables)")
+	flag.IntVar(&thresholds.MaxParameters, "max-params", 0, "maximum parameters per function (0 disables)")
+	flag.Float64Var(&thresholds.MinMaintainability, "min-mi", 0, "minimum maintainability index per function (0 disables)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: complexity [flags] [packages, directories or files]\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "complexity: %v\n", err)
+		os.Exit(2)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "max-cyclomatic":
+			cfg.Thresholds.MaxCyclomatic = thresholds.MaxCyclomatic
+		case "max-cognitive":
+			cfg.Thresholds.MaxCognitive = thresholds.MaxCognitive
+		case "max-nesting":
+			cfg.Thresholds.MaxNestedDepth = thresholds.MaxNestedDepth
+		case "max-params":
+			cfg.Thresholds.MaxParameters = thresholds.MaxParameters
+		case "min-mi":
+			cfg.Thresholds.MinMaintainability = thresholds.MinMaintainability
+		case "fold-closures":
+			cfg.FoldClosures = *foldClosures
+		case "types":
+			cfg.TypeCheck = *typeCheck
+		case "exclude":
+			cfg.ExcludeClasses = splitList(*exclude)
+		}
+	})
+	if err := config.ValidateClasses(cfg.ExcludeClasses); err != nil {
+		fmt.Fprintf(os.Stderr, "complexity: -exclude: %v\n", err)
+		os.Exit(2)
+	}
+	switch {
+	case *coupling && *cohesion:
+		fmt.Fprintln(os.Stderr, "complexity: -coupling and -cohesion cannot be combined")
+		os.Exit(2)
+	case (*coupling || *cohesion) && *summary != "":
+		fmt.Fprintln(os.Stderr, "complexity: -summary cannot be combined with -coupling or -cohesion")
+		os.Exit(2)
+	case (*coupling || *cohesion) && *format == "sarif":
+		fmt.Fprintln(os.Stderr, "complexity: -coupling and -cohesion support the table and json formats only")
+		os.Exit(2)
+	}
+
+	if *diff != "" {
+		if flag.NArg() > 0 {
+			fmt.Fprintln(os.Stderr, "complexity: -diff analyzes the changed files of the repository and takes no patterns")
+			os.Exit(2)
+		}
+		// The other modes do not apply to a comparison
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "baseline", "write-baseline", "tighten", "store", "project", "revision", "summary", "coupling", "cohesion", "explain":
+				fmt.Fprintf(os.Stderr, "complexity: -%s cannot be combined with -diff\n", f.Name)
+				os.Exit(2)
+			}
+		})
+		os.Exit(runDiff(*diff, *format, cfg))
+	}
+
+	files, err := analyzer.FindGoFiles(flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "complexity: %v\n", err)
+		os.Exit(2)
+	}
+
+	results, analyzers, failed := analyzeFiles(files, cfg)
+	violations, suppressed := analyzer.SplitSuppressed(cfg.Check(allFunctions(results)))
+
+	if (*baselinePath != "" || *writeBaseline != "") && !cfg.ThresholdsEnabled() {
+		fmt.Fprintln(os.Stderr, "complexity: a baseline requires at least one -max-* or -min-* limit")
+		os.Exit(2)
+	}
+	if *writeBaseline != "" {
+		if failed {
+			fmt.Fprintln(os.Stderr, "complexity: not writing the baseline, some files could not be analyzed")
+			os.Exit(2)
+		}
+		if err := analyzer.NewBaseline(violations).WriteFile(*writeBaseline); err != nil {
+			fmt.Fprintf(os.Stderr, "complexity: %v\n", err)
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "complexity: recorded %d violations in %s\n", len(violations), *writeBaseline)
+		os.Exit(0)
+	}
+	if *baselinePath != "" {
+		if *tighten && failed {
+			fmt.Fprintln(os.Stderr, "complexity: not tightening the baseline, some files could not be analyzed")
+			os.Exit(2)
+		}
+		if violations, err = applyBaseline(*baselinePath, *tighten, allFunctions(results), violations); err != nil {
+			fmt.Fprintf(os.Stderr, "complexity: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	if *storeDir != "" {
+		if err := recordRun(*storeDir, *project, *revision, allFunctions(results)); err != nil {
+			fmt.Fprintf(os.Stderr, "complexity: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	var summaries []*analyzer.Summary
+	switch *summary {
+	case "":
+	case "file":
+		summaries = analyzer.SummarizeFiles(allFunctions(results))
+	case "package":
+		summaries = analyzer.SummarizePackages(allFunctions(results))
+	case "class":
+		summaries = analyzer.SummarizeClasses(allFunctions(results))
+	default:
+		fmt.Fprintf(os.Stderr, "complexity: unknown summary %q\n", *summary)
+		os.Exit(2)
+	}
+
+	switch {
+	case *coupling && *format == "table":
+		printCouplingTable(os.Stdout, analyzer.Coupling(analyzers))
+	case *coupling && *format == "json":
+		err = writeJSON(os.Stdout, analyzer.Coupling(analyzers))
+	case *cohesion && *format == "table":
+		printCohesionTable(os.Stdout, analyzer.Cohesion(analyzers))
+	case *cohesion && *format == "json":
+		err = writeJSON(os.Stdout, analyzer.Cohesion(analyzers))
+	case *summary != "" && *format == "table":
+		printSummaryTable(os.Stdout, summaries)
+	case *summary != "" && *format == "json":
+		err = writeJSON(os.Stdout, summaries)
+	case *format == "table":
+		printTable(os.Stdout, results, cfg.TypeCheck)
+		if *explain {
+			printExplanations(os.Stdout, results)
+		}
+	case *format == "json":
+		err = writeJSON(os.Stdout, results)
+	case *format == "sarif":
+		err = writeJSON(os.Stdout, report.SARIF(".", allFunctions(results), append(violations, suppressed...)))
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "complexity: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Files that failed do not hide the violations of the others
+	for _, v := range violations {
+		fmt.Fprintln(os.Stderr, v)
+	}
+	if failed {
+		os.Exit(2)
+	}
+	if len(violations) > 0 {
+		os.Exit(1)
+	}
+}
+
+// applyBaseline returns the violations of results the baseline at path does
+// not accept and reports the entries that improved on stderr. With tighten,
+// the file is rewritten with the improved values.
+func applyBaseline(path string, tighten bool, results []*analyzer.MetricsResult, violations []analyzer.Violation) ([]analyzer.Violation, error) {
+	baseline, err := analyzer.ReadBaseline(path)
+	if err != nil {
+		return nil, err
+	}
+	check := baseline.Check(results, violations)
+
+	for _, entry := range check.Improved {
+		if entry.Fixed {
+			fmt.Fprintf(os.Stderr, "baseline: %s: %s fixed (was %g)\n", entry.Function, entry.Metric, entry.Value)
+		} else {
+			fmt.Fprintf(os.Stderr, "baseline: %s: %s improved from %g to %g\n", entry.Function, entry.Metric, entry.Value, entry.Current)
+		}
+	}
+	if len(check.Improved) > 0 {
+		if tighten {
+			if err := baseline.Tighten(check).WriteFile(path); err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(os.Stderr, "baseline: tightened %d entries in %s\n", len(check.Improved), path)
+		} else {
+			fmt.Fprintln(os.Stderr, "baseline: run with -tighten to lower the baseline")
+		}
+	}
+
+	return append(check.New, check.Worse...), nil
+}
+
+// recordRun saves results as a run of project in the store at dir.
+func recordRun(dir, project, revision string, results []*analyzer.MetricsResult) error {
+	if project == "" {
+		project = analyzer.ImportPath(".")
+	}
+	if project == "" {
+		return fmt.Errorf("-project is required outside a module")
+	}
+
+	runs, err := store.NewFileStore(dir)
+	if err != nil {
+		return err
+	}
+	return runs.Save(&store.Run{Project: project, Revision: revision, Results: results})
+}
+
+// loadConfig reads the configuration file at path, or the one found from
+// the current directory when path is empty. Without either it returns the
+// default configuration.
+func loadConfig(path string) (*config.Config, error) {
+	if path == "" {
+		var err error
+		if path, err = config.Find("."); err != nil {
+			return nil, err
+		}
+		if path == "" {
+			return config.Default(), nil
+		}
+	}
+	return config.Load(path)
+}
+
+// analyzeFiles runs the analyzer on every file the configuration includes
+// and whose class it does not exclude, and returns the results along with
+// the analyzers, from which package and type metrics are computed. Files
+// that cannot be read or parsed are reported on stderr and skipped; failed
+// reports whether any were.
+func analyzeFiles(files []string, cfg *config.Config) (results []fileResult, analyzers []*analyzer.FileAnalyzer, failed bool) {
+	fset := token.NewFileSet()
+	importPaths := make(map[string]string)
+	for _, file := range files {
+		if !cfg.Includes(file) || cfg.ExcludesClass(analyzer.ClassifyPath(file)) {
+			continue
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "complexity: %v\n", err)
+			failed = true
+			continue
+		}
+
+		fileAnalyzer, err := analyzer.ParseFile(fset, file, content)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "complexity: %v\n", err)
+			failed = true
+			continue
+		}
+		if cfg.ExcludesClass(fileAnalyzer.Classification()) {
+			continue
+		}
+
+		fileAnalyzer.SetFoldClosures(cfg.FoldClosures)
+		fileAnalyzer.SetMetrics(cfg.Metrics)
+
+		dir := filepath.Dir(file)
+		if _, ok := importPaths[dir]; !ok {
+			importPaths[dir] = analyzer.ImportPath(dir)
+		}
+		if importPath := importPaths[dir]; importPath != "" {
+			fileAnalyzer.SetPackagePath(importPath)
+		}
+		analyzers = append(analyzers, fileAnalyzer)
+	}
+	analyzer.NumberInits(analyzers)
+
+	if cfg.TypeCheck {
+		fallback := importer.ForCompiler(fset, "source", nil).(types.ImporterFrom)
+		for _, pkg := range analyzer.LoadPackages(fset, analyzers, fallback) {
+			if len(pkg.Errors) > 0 {
+				fmt.Fprintf(os.Stderr, "complexity: warning: %v (%d type errors in %s)\n", pkg.Errors[0], len(pkg.Errors), pkg.Path)
+			}
+		}
+	}
+
+	for _, fileAnalyzer := range analyzers {
+		for _, err := range fileAnalyzer.DirectiveErrors() {
+			fmt.Fprintf(os.Stderr, "complexity: warning: %v\n", err)
+		}
+		functions := fileAnalyzer.AnalyzeFile()
+		if len(functions) == 0 {
+			continue
+		}
+		results = append(results, fileResult{File: fileAnalyzer.Filename(), Functions: functions})
+	}
+	if cfg.TypeCheck {
+		analyzer.LinkCalls(allFunctions(results))
+	}
+	return results, analyzers, failed
+}
+
+// splitList splits a comma-separated flag value, dropping empty elements.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// allFunctions flattens the per-file results.
+func allFunctions(results []fileResult) []*analyzer.MetricsResult {
+	var functions []*analyzer.MetricsResult
+	for _, file := range results {
+		functions = append(functions, file.Functions...)
+	}
+	return functions
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(out io.Writer, v interface{}) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printTable writes one row per function, with the call metrics if calls
+// are linked.
+func printTable(out io.Writer, results []fileResult, calls bool) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := "LOCATION\tFUNCTION\tCYCLO\tNPATH\tCOGN\tLOC\tMI\tNEST\tPARAMS"
+	if calls {
+		header += "\tFAN-IN\tFAN-OUT\tIFLOW"
+	}
+	fmt.Fprintln(w, header)
+	for _, file := range results {
+		for _, fn := range file.Functions {
+			fmt.Fprintf(w, "%s:%d\t%s\t%d\t%d\t%d\t%d\t%.0f\t%d\t%d",
+				fn.File, fn.Line, fn.DisplayName(), fn.CyclomaticComplexity, fn.NPathComplexity, fn.CognitiveComplexity,
+				fn.LinesOfCode, fn.MaintainabilityIndex, fn.NestedDepth, fn.FunctionParameters)
+			if calls {
+				fmt.Fprintf(w, "\t%d\t%d\t%d", fn.FanIn, fn.FanOut, fn.InformationFlow)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	w.Flush()
+}
+
+// printCouplingTable writes one row per package, farthest from the main
+// sequence first and packages without a distance last. Undefined metrics
+// are shown as N/A.
+func printCouplingTable(out io.Writer, packages []*analyzer.PackageMetrics) {
+	sorted := append([]*analyzer.PackageMetrics(nil), packages...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Distance == nil || sorted[j].Distance == nil {
+			return sorted[j].Distance == nil && sorted[i].Distance != nil
+		}
+		return *sorted[i].Distance > *sorted[j].Distance
+	})
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PACKAGE\tCA\tCE\tI\tTYPES\tIFACES\tA\tD")
+	for _, p := range sorted {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%d\t%d\t%s\t%s\n",
+			p.Package, p.Afferent, p.Efferent, formatRatio(p.Instability), p.Types, p.Interfaces,
+			formatRatio(p.Abstractness), formatRatio(p.Distance))
+	}
+	w.Flush()
+}
+
+// formatRatio formats a coupling metric, N/A if it is undefined.
+func formatRatio(r *float64) string {
+	if r == nil {
+		return "N/A"
+	}
+	return fmt.Sprintf("%.2f", *r)
+}
+
+// printCohesionTable writes one row per struct type, god types first, then
+// the least cohesive.
+func printCohesionTable(out io.Writer, types []*analyzer.TypeCohesion) {
+	sorted := append([]*analyzer.TypeCohesion(nil), types...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].GodType != sorted[j].GodType {
+			return sorted[i].GodType
+		}
+		if sorted[i].LCOM4 != sorted[j].LCOM4 {
+			return sorted[i].LCOM4 > sorted[j].LCOM4
+		}
+		return sorted[i].Methods > sorted[j].Methods
+	})
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LOCATION\tTYPE\tMETHODS\tFIELDS\tLCOM4\tGOD")
+	for _, t := range sorted {
+		god := ""
+		if t.GodType {
+			god = "yes"
+		}
+		fmt.Fprintf(w, "%s:%d\t%s\t%d\t%d\t%d\t%s\n", t.File, t.Line, t.Type, t.Methods, t.Fields, t.LCOM4, god)
+	}
+	w.Flush()
+}
+
+// printSummaryTable writes one row per file or package, most complex first.
+func printSummaryTable(out io.Writer, summaries []*analyzer.Summary) {
+	sorted := append([]*analyzer.Summary(nil), summaries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Cognitive.Total > sorted[j].Cognitive.Total
+	})
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tFUNCS\tLOC\tCYCLO\tCYCLO AVG\tCYCLO MAX\tCYCLO P90\tCOGN\tCOGN AVG\tCOGN MAX\tCOGN P90\tMI AVG\tWORST")
+	for _, s := range sorted {
+		worst := ""
+		if len(s.WorstOffenders) > 0 {
+			worst = s.WorstOffenders[0].DisplayName()
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.2f\t%d\t%.0f\t%d\t%.2f\t%d\t%.0f\t%.2f\t%s\n",
+			s.Name, s.Functions, s.LinesOfCode,
+			s.Cyclomatic.Total, s.Cyclomatic.Average, s.Cyclomatic.Max, s.Cyclomatic.P90,
+			s.Cognitive.Total, s.Cognitive.Average, s.Cognitive.Max, s.Cognitive.P90,
+			s.AverageMaintainability, worst)
+	}
+	w.Flush()
+}
+
+// printExplanations lists the cognitive complexity increments of every
+// function that has any.
+func printExplanations(out io.Writer, results []fileResult) {
+	for _, file := range results {
+		for _, fn := range file.Functions {
+			if len(fn.CognitiveIncrements) == 0 {
+				continue
+			}
+			fmt.Fprintf(out, "\n%s:%d: %s: cognitive complexity %d\n", fn.File, fn.Line, fn.DisplayName(), fn.CognitiveComplexity)
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			for _, increment := range fn.CognitiveIncrements {
+				fmt.Fprintf(w, "    line %d\t%s\t+%d", increment.Line, increment.Construct, increment.Base)
+				if increment.Nesting > 0 {
+					fmt.Fprintf(w, "\t(nesting +%d)", increment.Nesting)
+				}
+				fmt.Fprintln(w)
+			}
+			w.Flush()
+		}
+	}
+}
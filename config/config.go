@@ -0,0 +1,369 @@
+// Package config loads the project configuration shared by the server and
+// the command line: which metrics to compute, thresholds, the files to
+// analyze, per-path overrides and per-function exemptions.
+//
+// A configuration file looks like this:
+//
+//	metrics: [cyclomatic, cognitive, maintainability, lines, nesting, parameters]
+//	thresholds:
+//	  maxCyclomatic: 15
+//	  maxCognitive: 20
+//	include: ["*.go"]
+//	exclude: ["internal/mocks/**"]
+//	excludeClasses: [vendor, testdata, generated]
+//	overrides:
+//	  - paths: ["internal/generated/**"]
+//	    thresholds:
+//	      maxCyclomatic: 60
+//	      maxCognitive: -1
+//	exemptions:
+//	  - function: example.com/app/parser.(*Parser).parseExpr
+//	    metrics: [cyclomatic]
+//	    reason: generated by a table-driven state machine
+//	server:
+//	  maxUploadSize: 5MB
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the configuration file Find looks for.
+const FileName = ".complexity.yaml"
+
+// Config is a project configuration.
+type Config struct {
+	// Metrics lists the metrics to compute, every metric when empty.
+	Metrics []string `yaml:"metrics"`
+	// Thresholds apply to every function unless overridden.
+	Thresholds analyzer.Thresholds `yaml:"thresholds"`
+	// Include lists the file patterns to analyze; Exclude removes files
+	// from them. See Match for the pattern syntax.
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+	// ExcludeClasses lists the classes of files left out of reports, see
+	// analyzer.Classes. An empty list keeps every class.
+	ExcludeClasses []string `yaml:"excludeClasses"`
+	// Overrides change thresholds for the files matching their paths.
+	Overrides []Override `yaml:"overrides"`
+	// Exemptions accept violations of individual functions.
+	Exemptions []Exemption `yaml:"exemptions"`
+	// FoldClosures counts closures towards their enclosing function.
+	FoldClosures bool `yaml:"foldClosures"`
+	// TypeCheck type-checks packages before analyzing them, see
+	// analyzer.LoadPackages.
+	TypeCheck bool `yaml:"typeCheck"`
+	// Server configures the web server.
+	Server Server `yaml:"server"`
+
+	root string
+}
+
+// Override replaces the non-zero thresholds of the files matching Paths. A
+// negative threshold disables the check.
+type Override struct {
+	Paths      []string            `yaml:"paths"`
+	Thresholds analyzer.Thresholds `yaml:"thresholds"`
+}
+
+// Exemption accepts the violations of a function, identified by its
+// qualified name. A name ending in * exempts every function starting with
+// the rest of it. Empty Metrics exempt every metric.
+type Exemption struct {
+	Function string   `yaml:"function"`
+	Metrics  []string `yaml:"metrics"`
+	Reason   string   `yaml:"reason"`
+}
+
+// Server holds the limits of the web server.
+type Server struct {
+	MaxUploadSize    Size `yaml:"maxUploadSize"`    // Request size of /analyze and /analyze/lines.
+	MaxJobUploadSize Size `yaml:"maxJobUploadSize"` // Request size of /jobs and /analyze/stream.
+	MaxExtractedSize Size `yaml:"maxExtractedSize"` // Sources extracted from the archives of a request.
+}
+
+// Default returns the configuration used without a configuration file.
+func Default() *Config {
+	return &Config{
+		Include:        []string{"*.go"},
+		ExcludeClasses: []string{analyzer.ClassVendor, analyzer.ClassTestdata, analyzer.ClassGenerated},
+		Server: Server{
+			MaxUploadSize:    5 << 20,
+			MaxJobUploadSize: 50 << 20,
+			MaxExtractedSize: 50 << 20,
+		},
+	}
+}
+
+// Parse reads a configuration, filling in defaults for what it leaves out.
+// Paths are matched as given.
+func Parse(r io.Reader) (*Config, error) {
+	c := Default()
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	defaults := Default()
+	if len(c.Include) == 0 {
+		c.Include = defaults.Include
+	}
+	if c.Server.MaxUploadSize <= 0 {
+		c.Server.MaxUploadSize = defaults.Server.MaxUploadSize
+	}
+	if c.Server.MaxJobUploadSize <= 0 {
+		c.Server.MaxJobUploadSize = defaults.Server.MaxJobUploadSize
+	}
+	if c.Server.MaxExtractedSize <= 0 {
+		c.Server.MaxExtractedSize = defaults.Server.MaxExtractedSize
+	}
+
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Load reads the configuration file at path. Relative file paths given to
+// the returned configuration are resolved against the working directory and
+// matched relative to the directory of the file.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if c.root, err = filepath.Abs(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Find returns the path of the configuration file in dir or the closest of
+// its parents, or "" if there is none.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+func (c *Config) validate() error {
+	known := make(map[string]bool, len(analyzer.Metrics))
+	for _, metric := range analyzer.Metrics {
+		known[metric] = true
+	}
+	for _, metric := range c.Metrics {
+		if !known[metric] {
+			return fmt.Errorf("unknown metric %q", metric)
+		}
+	}
+	if err := ValidateClasses(c.ExcludeClasses); err != nil {
+		return err
+	}
+	for _, exemption := range c.Exemptions {
+		if exemption.Function == "" {
+			return errors.New("exemption without a function")
+		}
+		for _, metric := range exemption.Metrics {
+			if !known[metric] {
+				return fmt.Errorf("exemption of %s: unknown metric %q", exemption.Function, metric)
+			}
+		}
+	}
+	for _, override := range c.Overrides {
+		if len(override.Paths) == 0 {
+			return errors.New("override without paths")
+		}
+	}
+	return nil
+}
+
+// ValidateClasses returns an error if classes holds an unknown file class.
+func ValidateClasses(classes []string) error {
+	for _, class := range classes {
+		if !analyzer.ContainsClass(analyzer.Classes, class) {
+			return fmt.Errorf("unknown file class %q", class)
+		}
+	}
+	return nil
+}
+
+// Options returns the analysis options of the configuration.
+func (c *Config) Options() analyzer.Options {
+	return analyzer.Options{
+		Metrics:        c.Metrics,
+		FoldClosures:   c.FoldClosures,
+		ExcludeClasses: c.ExcludeClasses,
+		TypeCheck:      c.TypeCheck,
+	}
+}
+
+// Includes reports whether the file at path is to be analyzed.
+func (c *Config) Includes(path string) bool {
+	rel := c.relative(path)
+	return matchAny(c.Include, rel) && !matchAny(c.Exclude, rel)
+}
+
+// ExcludesClass reports whether files of class are left out of reports.
+func (c *Config) ExcludesClass(class string) bool {
+	return analyzer.ContainsClass(c.ExcludeClasses, class)
+}
+
+// ThresholdsFor returns the thresholds of the file at path: the overrides
+// matching it, in order, applied to the global thresholds. Thresholds of
+// metrics that are not computed are cleared.
+func (c *Config) ThresholdsFor(path string) analyzer.Thresholds {
+	t := c.Thresholds
+	rel := c.relative(path)
+	for _, override := range c.Overrides {
+		if matchAny(override.Paths, rel) {
+			t = merge(t, override.Thresholds)
+		}
+	}
+
+	if !c.computes(analyzer.MetricCyclomatic) {
+		t.MaxCyclomatic = 0
+	}
+	if !c.computes(analyzer.MetricCognitive) {
+		t.MaxCognitive = 0
+	}
+	if !c.computes(analyzer.MetricNesting) {
+		t.MaxNestedDepth = 0
+	}
+	if !c.computes(analyzer.MetricParameters) {
+		t.MaxParameters = 0
+	}
+	if !c.computes(analyzer.MetricMaintainability) {
+		t.MinMaintainability = 0
+	}
+	return t
+}
+
+// ThresholdsEnabled reports whether any threshold is set, globally or in an
+// override.
+func (c *Config) ThresholdsEnabled() bool {
+	if c.Thresholds.Enabled() {
+		return true
+	}
+	for _, override := range c.Overrides {
+		if override.Thresholds.Enabled() {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns the violations of results under the thresholds of their
+// files. Exempted violations not already suppressed in the source are marked
+// suppressed by the configuration.
+func (c *Config) Check(results []*analyzer.MetricsResult) []analyzer.Violation {
+	var violations []analyzer.Violation
+	for _, result := range results {
+		for _, v := range c.ThresholdsFor(result.File).CheckFunction(result) {
+			if exemption := c.Exempt(v); exemption != nil && !v.Suppressed {
+				v.Suppress(analyzer.SuppressedByConfig, exemption.Reason)
+			}
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+// Exempt returns the exemption accepting v, or nil.
+func (c *Config) Exempt(v analyzer.Violation) *Exemption {
+	for i, exemption := range c.Exemptions {
+		if !matchFunction(exemption.Function, v.QualifiedName) {
+			continue
+		}
+		if len(exemption.Metrics) == 0 {
+			return &c.Exemptions[i]
+		}
+		for _, metric := range exemption.Metrics {
+			if metric == v.Metric {
+				return &c.Exemptions[i]
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) computes(metric string) bool {
+	if len(c.Metrics) == 0 {
+		return true
+	}
+	for _, m := range c.Metrics {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// relative returns path as a slash path relative to the directory of the
+// configuration file, or as given when that is not possible.
+func (c *Config) relative(path string) string {
+	if c.root == "" {
+		return filepath.ToSlash(path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	rel, err := filepath.Rel(c.root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// merge returns base with the non-zero thresholds of override.
+func merge(base, override analyzer.Thresholds) analyzer.Thresholds {
+	if override.MaxCyclomatic != 0 {
+		base.MaxCyclomatic = override.MaxCyclomatic
+	}
+	if override.MaxCognitive != 0 {
+		base.MaxCognitive = override.MaxCognitive
+	}
+	if override.MaxNestedDepth != 0 {
+		base.MaxNestedDepth = override.MaxNestedDepth
+	}
+	if override.MaxParameters != 0 {
+		base.MaxParameters = override.MaxParameters
+	}
+	if override.MinMaintainability != 0 {
+		base.MinMaintainability = override.MinMaintainability
+	}
+	return base
+}
+
+func matchFunction(pattern, name string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(name, prefix)
+	}
+	return pattern == name
+}
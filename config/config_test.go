@@ -0,0 +1,235 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+		check   func(t *testing.T, c *Config)
+	}{
+		{
+			name: "empty file keeps the defaults",
+			yaml: "",
+			check: func(t *testing.T, c *Config) {
+				if !reflect.DeepEqual(c, Default()) {
+					t.Errorf("config = %+v, want the defaults", c)
+				}
+			},
+		},
+		{
+			name: "sizes with units",
+			yaml: "server:\n  maxUploadSize: 5MB\n  maxJobUploadSize: 512kb\n  maxExtractedSize: 1 GB\n",
+			check: func(t *testing.T, c *Config) {
+				want := Server{MaxUploadSize: 5 << 20, MaxJobUploadSize: 512 << 10, MaxExtractedSize: 1 << 30}
+				if c.Server != want {
+					t.Errorf("server = %+v, want %+v", c.Server, want)
+				}
+			},
+		},
+		{
+			name: "sizes in bytes",
+			yaml: "server:\n  maxUploadSize: 1024\n  maxJobUploadSize: 2048B\n",
+			check: func(t *testing.T, c *Config) {
+				if c.Server.MaxUploadSize != 1024 || c.Server.MaxJobUploadSize != 2048 {
+					t.Errorf("server = %+v, want 1024 and 2048 bytes", c.Server)
+				}
+				if c.Server.MaxExtractedSize != Default().Server.MaxExtractedSize {
+					t.Errorf("maxExtractedSize = %d, want the default", c.Server.MaxExtractedSize)
+				}
+			},
+		},
+		{
+			name:    "invalid size",
+			yaml:    "server:\n  maxUploadSize: 5XB\n",
+			wantErr: `line 2: invalid size "5XB"`,
+		},
+		{
+			name:    "negative size",
+			yaml:    "server:\n  maxUploadSize: -5MB\n",
+			wantErr: `invalid size "-5MB"`,
+		},
+		{
+			name:    "unknown field",
+			yaml:    "thresholds:\n  maxCyclomatic: 10\n  maxComplexity: 10\n",
+			wantErr: "field maxComplexity not found",
+		},
+		{
+			name:    "unknown top-level field",
+			yaml:    "foldClosure: true\n",
+			wantErr: "field foldClosure not found",
+		},
+		{
+			name:    "unknown metric",
+			yaml:    "metrics: [cyclomatic, complexity]\n",
+			wantErr: `unknown metric "complexity"`,
+		},
+		{
+			name:    "unknown class",
+			yaml:    "excludeClasses: [tests]\n",
+			wantErr: `unknown file class "tests"`,
+		},
+		{
+			name:    "exemption without a function",
+			yaml:    "exemptions:\n  - metrics: [cognitive]\n",
+			wantErr: "exemption without a function",
+		},
+		{
+			name:    "exemption of an unknown metric",
+			yaml:    "exemptions:\n  - function: pkg.F\n    metrics: [cognitve]\n",
+			wantErr: `exemption of pkg.F: unknown metric "cognitve"`,
+		},
+		{
+			name:    "override without paths",
+			yaml:    "overrides:\n  - thresholds:\n      maxCyclomatic: 20\n",
+			wantErr: "override without paths",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse(strings.NewReader(tt.yaml))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestThresholdsFor(t *testing.T) {
+	const yaml = `
+metrics: [cyclomatic, cognitive, nesting, parameters, maintainability]
+thresholds:
+  maxCyclomatic: 10
+  maxCognitive: 15
+  maxNestedDepth: 4
+  maxParameters: 5
+  minMaintainability: 20
+overrides:
+  - paths: ["**/legacy"]
+    thresholds:
+      maxCyclomatic: 30
+      maxCognitive: -1
+  - paths: ["*_test.go"]
+    thresholds:
+      maxParameters: -1
+      minMaintainability: 10
+`
+	c, err := Parse(strings.NewReader(yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	global := c.Thresholds
+
+	tests := []struct {
+		path string
+		want analyzer.Thresholds
+	}{
+		{"pkg/a.go", global},
+		{"pkg/legacy/a.go", analyzer.Thresholds{
+			MaxCyclomatic: 30, MaxCognitive: -1, MaxNestedDepth: 4, MaxParameters: 5, MinMaintainability: 20,
+		}},
+		{"pkg/a_test.go", analyzer.Thresholds{
+			MaxCyclomatic: 10, MaxCognitive: 15, MaxNestedDepth: 4, MaxParameters: -1, MinMaintainability: 10,
+		}},
+		// Both overrides apply, in order.
+		{"legacy/a_test.go", analyzer.Thresholds{
+			MaxCyclomatic: 30, MaxCognitive: -1, MaxNestedDepth: 4, MaxParameters: -1, MinMaintainability: 10,
+		}},
+	}
+	for _, tt := range tests {
+		if got := c.ThresholdsFor(tt.path); got != tt.want {
+			t.Errorf("ThresholdsFor(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+
+	// A disabled threshold reports nothing, however high the value.
+	result := &analyzer.MetricsResult{
+		File:                 "pkg/legacy/a.go",
+		QualifiedName:        "pkg.F",
+		CyclomaticComplexity: 12,
+		CognitiveComplexity:  100,
+		MaintainabilityIndex: 50,
+	}
+	for _, v := range c.Check([]*analyzer.MetricsResult{result}) {
+		t.Errorf("unexpected violation %+v", v)
+	}
+}
+
+func TestThresholdsForUncomputedMetrics(t *testing.T) {
+	c, err := Parse(strings.NewReader("metrics: [cyclomatic]\nthresholds:\n  maxCyclomatic: 10\n  maxCognitive: 15\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := analyzer.Thresholds{MaxCyclomatic: 10}
+	if got := c.ThresholdsFor("a.go"); got != want {
+		t.Errorf("ThresholdsFor() = %+v, want %+v", got, want)
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	c, err := Parse(strings.NewReader("include: ['*.go']\nexclude: ['**/mocks', '*.pb.go', 'internal/gen']\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"main.go", true},
+		{"pkg/a.go", true},
+		{"pkg/README.md", false},
+		{"pkg/mocks/store.go", false},
+		{"mocks/store.go", false},
+		{"api/api.pb.go", false},
+		{"internal/gen/a.go", false},
+		{"internal/gen/deep/a.go", false},
+		{"internal/generator/a.go", true},
+	}
+	for _, tt := range tests {
+		if got := c.Includes(tt.path); got != tt.want {
+			t.Errorf("Includes(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestExempt(t *testing.T) {
+	c := &Config{Exemptions: []Exemption{
+		{Function: "pkg.(*Parser).parse*", Metrics: []string{analyzer.MetricCognitive}, Reason: "generated"},
+		{Function: "pkg.main", Reason: "entry point"},
+	}}
+	tests := []struct {
+		name   string
+		metric string
+		want   string
+	}{
+		{"pkg.(*Parser).parseExpr", analyzer.MetricCognitive, "generated"},
+		{"pkg.(*Parser).parse", analyzer.MetricCognitive, "generated"},
+		{"pkg.(*Parser).parseExpr", analyzer.MetricCyclomatic, ""},
+		{"pkg.(*Parser).Parse", analyzer.MetricCognitive, ""},
+		{"pkg.main", analyzer.MetricParameters, "entry point"},
+		{"pkg.mainLoop", analyzer.MetricParameters, ""},
+	}
+	for _, tt := range tests {
+		var got string
+		if exemption := c.Exempt(analyzer.Violation{QualifiedName: tt.name, Metric: tt.metric}); exemption != nil {
+			got = exemption.Reason
+		}
+		if got != tt.want {
+			t.Errorf("Exempt(%s, %s) = %q, want %q", tt.name, tt.metric, got, tt.want)
+		}
+	}
+}
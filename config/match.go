@@ -0,0 +1,57 @@
+package config
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether the slash-separated path name matches pattern.
+// Patterns use the syntax of path.Match, plus "**" segments matching any
+// number of directories. A pattern without a slash matches the base name,
+// and a pattern matching a directory matches every file below it.
+func Match(pattern, name string) bool {
+	name = path.Clean(name)
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+
+	patterns := strings.Split(strings.Trim(pattern, "/"), "/")
+	segments := strings.Split(name, "/")
+	for n := len(segments); n > 0; n-- {
+		if matchSegments(patterns, segments[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(patterns, segments []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(patterns[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(patterns[0], segments[0]); !ok {
+			return false
+		}
+		patterns, segments = patterns[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if Match(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package config
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/complexity/main.go", true},
+		{"*_test.go", "pkg/a.go", false},
+		{"internal/mocks", "internal/mocks/store.go", true},
+		{"internal/mocks", "internal/mocks/deep/store.go", true},
+		{"internal/mocks", "internal/mocksx/store.go", false},
+		{"internal/mocks", "pkg/internal/mocks/store.go", false},
+		{"internal/*/store.go", "internal/mocks/store.go", true},
+		{"internal/*/store.go", "internal/a/b/store.go", false},
+		{"**/generated", "a/b/generated/x.go", true},
+		{"**/generated", "generated/x.go", true},
+		{"internal/**/*.pb.go", "internal/api/v1/api.pb.go", true},
+		{"internal/**/*.pb.go", "internal/api.pb.go", true},
+		{"internal/**/*.pb.go", "api/v1/api.pb.go", false},
+		{"/internal/", "internal/x.go", true},
+		{"internal/mocks", "./internal/mocks/../mocks/x.go", true},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
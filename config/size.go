@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Size is a number of bytes, written in a configuration file either as a
+// number or with a KB, MB or GB suffix.
+type Size int64
+
+// UnmarshalYAML parses sizes such as 5242880, 512KB or 5MB.
+func (s *Size) UnmarshalYAML(value *yaml.Node) error {
+	text := strings.ToUpper(strings.TrimSpace(value.Value))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"B", 1}} {
+		if trimmed, ok := strings.CutSuffix(text, unit.suffix); ok {
+			text, multiplier = strings.TrimSpace(trimmed), unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("line %d: invalid size %q", value.Line, value.Value)
+	}
+	*s = Size(n * multiplier)
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/aman/code-complexity-viz/jobs"
+	"github.com/gin-gonic/gin"
+)
+
+// JobCreatedResponse is returned when an analysis job is queued.
+type JobCreatedResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+func handleCreateJob(manager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sources, ok := readSources(c, int64(cfg.Server.MaxJobUploadSize))
+		if !ok {
+			return
+		}
+
+		id, err := manager.Submit(sources)
+		if err != nil {
+			log.Printf("Error submitting job: %v", err)
+			status := http.StatusInternalServerError
+			if errors.Is(err, jobs.ErrQueueFull) {
+				status = http.StatusServiceUnavailable
+			}
+			c.JSON(status, ErrorResponse{
+				Error: "Failed to queue job: " + err.Error(),
+			})
+			return
+		}
+
+		c.Header("Location", "/jobs/"+id)
+		c.JSON(http.StatusAccepted, JobCreatedResponse{ID: id, Status: jobs.StatusQueued})
+	}
+}
+
+func handleGetJob(manager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		snapshot, ok := manager.Get(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error: "Job not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, snapshot)
+	}
+}
+
+func handleCancelJob(manager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		snapshot, ok := manager.Cancel(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error: "Job not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, snapshot)
+	}
+}
@@ -0,0 +1,330 @@
+// Package jobs runs analyses asynchronously on a bounded pool of workers.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+)
+
+// Job states.
+const (
+	StatusQueued   = "queued"
+	StatusRunning  = "running"
+	StatusDone     = "done"
+	StatusFailed   = "failed"
+	StatusCanceled = "canceled"
+)
+
+// retention is how long finished jobs remain available, and pruneInterval
+// how often they are looked for.
+const (
+	retention     = time.Hour
+	pruneInterval = time.Minute
+)
+
+var (
+	// ErrQueueFull is returned by Submit when no more jobs can be queued.
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrClosed is returned by Submit after Close.
+	ErrClosed = errors.New("job manager is closed")
+)
+
+// Progress counts the files a job has analyzed.
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// Snapshot is the state of a job at one point in time. Report holds the
+// results analyzed so far while the job is running.
+type Snapshot struct {
+	ID         string           `json:"id"`
+	Status     string           `json:"status"`
+	Progress   Progress         `json:"progress"`
+	Error      string           `json:"error,omitempty"`
+	CreatedAt  time.Time        `json:"createdAt"`
+	FinishedAt *time.Time       `json:"finishedAt,omitempty"`
+	Report     *analyzer.Report `json:"report,omitempty"`
+}
+
+// job is the mutable state of a submitted analysis.
+type job struct {
+	mu         sync.Mutex
+	id         string
+	status     string
+	sources    []analyzer.Source
+	files      []*analyzer.FileReport
+	progress   Progress
+	err        string
+	createdAt  time.Time
+	finishedAt time.Time
+	ctx        context.Context
+	cancel     context.CancelFunc
+	check      func([]*analyzer.MetricsResult) []analyzer.Violation
+
+	// The report of the first reportFiles files, reused until more files
+	// are analyzed.
+	report      *analyzer.Report
+	reportFiles int
+}
+
+// Manager queues jobs and runs them on a fixed number of workers.
+type Manager struct {
+	mu      sync.Mutex
+	jobs    map[string]*job
+	queue   chan *job
+	options analyzer.Options
+	check   func([]*analyzer.MetricsResult) []analyzer.Violation
+	closed  bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewManager starts workers goroutines consuming a queue of queueSize jobs,
+// which are analyzed with options. The reports of the jobs hold the
+// violations check returns, if not nil.
+func NewManager(workers, queueSize int, options analyzer.Options, check func([]*analyzer.MetricsResult) []analyzer.Violation) *Manager {
+	if workers < 1 {
+		workers = 1
+	}
+	m := newManager(queueSize, options, check)
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	m.wg.Add(1)
+	go m.pruneLoop()
+	return m
+}
+
+// newManager returns a manager without workers.
+func newManager(queueSize int, options analyzer.Options, check func([]*analyzer.MetricsResult) []analyzer.Violation) *Manager {
+	return &Manager{
+		jobs:    make(map[string]*job),
+		queue:   make(chan *job, queueSize),
+		options: options,
+		check:   check,
+		done:    make(chan struct{}),
+	}
+}
+
+// Submit queues the analysis of sources and returns the job ID.
+func (m *Manager) Submit(sources []analyzer.Source) (string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		id:        id,
+		status:    StatusQueued,
+		sources:   sources,
+		progress:  Progress{Total: analyzer.CountGoSources(sources)},
+		createdAt: time.Now(),
+		ctx:       ctx,
+		cancel:    cancel,
+		check:     m.check,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		cancel()
+		return "", ErrClosed
+	}
+	m.prune()
+
+	select {
+	case m.queue <- j:
+		m.jobs[id] = j
+		return id, nil
+	default:
+		cancel()
+		return "", ErrQueueFull
+	}
+}
+
+// Get returns a snapshot of the job with the given ID.
+func (m *Manager) Get(id string) (Snapshot, bool) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return Snapshot{}, false
+	}
+	return j.snapshot(), true
+}
+
+// Cancel stops the job with the given ID. A queued job never starts; a
+// running job stops after the function or package it is analyzing.
+// Finished jobs are left untouched.
+func (m *Manager) Cancel(id string) (Snapshot, bool) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return Snapshot{}, false
+	}
+
+	j.mu.Lock()
+	if j.status == StatusQueued {
+		j.finish(StatusCanceled, "")
+	}
+	j.mu.Unlock()
+	j.cancel()
+
+	return j.snapshot(), true
+}
+
+// Close cancels every job and waits for the workers to exit.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	for _, j := range m.jobs {
+		j.cancel()
+	}
+	close(m.queue)
+	close(m.done)
+	m.mu.Unlock()
+
+	m.wg.Wait()
+}
+
+// pruneLoop prunes finished jobs periodically until the manager is closed,
+// so that they expire even when no job is submitted.
+func (m *Manager) pruneLoop() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.mu.Lock()
+			m.prune()
+			m.mu.Unlock()
+		case <-m.done:
+			return
+		}
+	}
+}
+
+func (m *Manager) worker() {
+	defer m.wg.Done()
+	for j := range m.queue {
+		m.run(j)
+	}
+}
+
+// run analyzes the sources of a job, recording progress after every file.
+func (m *Manager) run(j *job) {
+	j.mu.Lock()
+	if j.status != StatusQueued {
+		j.mu.Unlock()
+		return
+	}
+	j.status = StatusRunning
+	sources := j.sources
+	j.mu.Unlock()
+
+	_, err := analyzer.AnalyzeSourcesContext(j.ctx, sources, m.options, func(file *analyzer.FileReport) {
+		j.mu.Lock()
+		j.files = append(j.files, file)
+		j.progress.Done++
+		j.mu.Unlock()
+	})
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch {
+	case errors.Is(err, context.Canceled):
+		j.finish(StatusCanceled, "")
+	case err != nil:
+		j.finish(StatusFailed, err.Error())
+	default:
+		j.finish(StatusDone, "")
+	}
+	j.cancel()
+}
+
+// prune forgets jobs that finished longer than the retention period ago.
+// The caller must hold m.mu.
+func (m *Manager) prune() {
+	cutoff := time.Now().Add(-retention)
+	for id, j := range m.jobs {
+		j.mu.Lock()
+		expired := !j.finishedAt.IsZero() && j.finishedAt.Before(cutoff)
+		j.mu.Unlock()
+		if expired {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// finish records the final state of the job. The caller must hold j.mu.
+func (j *job) finish(status, err string) {
+	j.status = status
+	j.err = err
+	j.finishedAt = time.Now()
+	j.sources = nil
+}
+
+// snapshot returns the state of the job. The report is rebuilt without
+// holding j.mu, and only when more files were analyzed since the last one.
+func (j *job) snapshot() Snapshot {
+	j.mu.Lock()
+	s := Snapshot{
+		ID:        j.id,
+		Status:    j.status,
+		Progress:  j.progress,
+		Error:     j.err,
+		CreatedAt: j.createdAt,
+	}
+	if !j.finishedAt.IsZero() {
+		finishedAt := j.finishedAt
+		s.FinishedAt = &finishedAt
+	}
+	if j.status == StatusQueued {
+		j.mu.Unlock()
+		return s
+	}
+	if j.report != nil && j.reportFiles == len(j.files) {
+		s.Report = j.report
+		j.mu.Unlock()
+		return s
+	}
+	// Files are only appended, so the first len(files) do not change
+	files := j.files[:len(j.files):len(j.files)]
+	j.mu.Unlock()
+
+	report := analyzer.NewReport(files)
+	if j.check != nil {
+		report.Violations = j.check(report.Functions())
+	}
+
+	j.mu.Lock()
+	if len(files) > j.reportFiles || j.report == nil {
+		j.report, j.reportFiles = report, len(files)
+	}
+	j.mu.Unlock()
+	s.Report = report
+	return s
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
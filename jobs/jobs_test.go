@@ -0,0 +1,152 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+)
+
+var sources = []analyzer.Source{
+	{Path: "go.mod", Content: []byte("module example\n")},
+	{Path: "a.go", Content: []byte("package example\n\nfunc A(x bool) {\n\tif x {\n\t}\n}\n")},
+	{Path: "b.go", Content: []byte("package example\n\nfunc B() {}\n")},
+}
+
+// checkCyclomatic reports the functions of cyclomatic complexity above 1.
+func checkCyclomatic(results []*analyzer.MetricsResult) []analyzer.Violation {
+	return analyzer.Thresholds{MaxCyclomatic: 1}.Check(results)
+}
+
+// wait polls the job until it finishes.
+func wait(t *testing.T, m *Manager, id string) Snapshot {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		snapshot, ok := m.Get(id)
+		if !ok {
+			t.Fatalf("job %s not found", id)
+		}
+		if snapshot.FinishedAt != nil {
+			return snapshot
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Snapshot{}
+}
+
+func TestRun(t *testing.T) {
+	m := NewManager(2, 4, analyzer.Options{}, checkCyclomatic)
+	defer m.Close()
+
+	id, err := m.Submit(sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := wait(t, m, id)
+	if snapshot.Status != StatusDone || snapshot.Progress != (Progress{Done: 2, Total: 2}) {
+		t.Errorf("status, progress = %s, %+v, want %s, {2 2}", snapshot.Status, snapshot.Progress, StatusDone)
+	}
+	if n := len(snapshot.Report.Functions()); n != 2 {
+		t.Errorf("got %d functions, want 2", n)
+	}
+	if v := snapshot.Report.Violations; len(v) != 1 || v[0].QualifiedName != "example.A" {
+		t.Errorf("violations = %+v, want one of example.A", v)
+	}
+
+	again, _ := m.Get(id)
+	if again.Report != snapshot.Report {
+		t.Error("report rebuilt without progress")
+	}
+}
+
+func TestQueue(t *testing.T) {
+	tests := []struct {
+		name   string
+		action func(m *Manager, ids []string)
+		want   []string // Status of every job once the worker ran.
+	}{
+		{"run", func(*Manager, []string) {}, []string{StatusDone, StatusDone}},
+		{"cancel queued", func(m *Manager, ids []string) { m.Cancel(ids[0]) }, []string{StatusCanceled, StatusDone}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Without workers until the queue is full
+			m := newManager(2, analyzer.Options{}, nil)
+			var ids []string
+			for i := 0; i < 2; i++ {
+				id, err := m.Submit(sources)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, id)
+			}
+			if _, err := m.Submit(sources); !errors.Is(err, ErrQueueFull) {
+				t.Errorf("submitting to a full queue: %v, want %v", err, ErrQueueFull)
+			}
+			if snapshot, _ := m.Get(ids[0]); snapshot.Status != StatusQueued || snapshot.Report != nil {
+				t.Errorf("queued job: status %s, report %v", snapshot.Status, snapshot.Report)
+			}
+
+			tt.action(m, ids)
+			m.wg.Add(1)
+			go m.worker()
+			for i, id := range ids {
+				if snapshot := wait(t, m, id); snapshot.Status != tt.want[i] {
+					t.Errorf("job %d: status %s, want %s", i, snapshot.Status, tt.want[i])
+				}
+			}
+
+			m.Close()
+			if _, err := m.Submit(sources); !errors.Is(err, ErrClosed) {
+				t.Errorf("submitting after Close: %v, want %v", err, ErrClosed)
+			}
+		})
+	}
+}
+
+func TestCancelFinished(t *testing.T) {
+	m := NewManager(1, 1, analyzer.Options{}, nil)
+	defer m.Close()
+
+	id, err := m.Submit(sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(t, m, id)
+	if snapshot, ok := m.Cancel(id); !ok || snapshot.Status != StatusDone {
+		t.Errorf("canceling a finished job: %s, %v, want %s", snapshot.Status, ok, StatusDone)
+	}
+	if _, ok := m.Cancel("unknown"); ok {
+		t.Error("canceled an unknown job")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	tests := []struct {
+		name     string
+		finished time.Duration // Ago, 0 for a running job.
+		kept     bool
+	}{
+		{"running", 0, true},
+		{"recent", time.Minute, true},
+		{"expired", retention + time.Minute, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newManager(1, analyzer.Options{}, nil)
+			j := &job{id: "job", status: StatusRunning}
+			if tt.finished > 0 {
+				j.status = StatusDone
+				j.finishedAt = time.Now().Add(-tt.finished)
+			}
+			m.jobs[j.id] = j
+			m.prune()
+			if _, ok := m.Get(j.id); ok != tt.kept {
+				t.Errorf("kept = %v, want %v", ok, tt.kept)
+			}
+		})
+	}
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -14,16 +18,30 @@ import (
 	"github.com/gin-contrib/secure"
 	"github.com/gin-gonic/gin"
 	"github.com/aman/code-complexity-viz/analyzer"
+	"github.com/aman/code-complexity-viz/config"
+	"github.com/aman/code-complexity-viz/jobs"
+	"github.com/aman/code-complexity-viz/store"
 )
 
-const (
-	maxFileSize = 5 << 20 // 5 MB
-)
+const maxQueuedJobs = 100
+
+// shutdownTimeout is how long requests in flight may take to finish once
+// the server is asked to stop.
+const shutdownTimeout = 30 * time.Second
+
+// cfg holds the settings of the server, read at startup by loadConfig.
+var cfg = config.Default()
 
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// LinesResponse holds the per-line metrics of an analyzed file.
+type LinesResponse struct {
+	File  string                 `json:"file"`
+	Lines []analyzer.LineMetrics `json:"lines"`
+}
+
 func init() {
 	// Create required directories if they don't exist
 	dirs := []string{"static", "templates", "logs"}
@@ -45,7 +63,10 @@ func setupRouter() *gin.Engine {
 	// Middleware
 	r.Use(gin.Recovery())
 	r.Use(gin.Logger())
-	r.Use(gzip.Gzip(gzip.DefaultCompression))
+	r.Use(uploadDeadlines)
+	// Event streams must reach the client unbuffered, and the call graph
+	// clears its write deadline, which gzip hides
+	r.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedPaths([]string{"/analyze/stream", "/callgraph"})))
 	r.Use(cors.Default())
 	r.Use(secure.New(secure.Config{
 		AllowedHosts:          []string{"localhost:8080"},
@@ -81,11 +102,41 @@ func setupLogger() *os.File {
 	return logFile
 }
 
+// loadConfig reads the configuration file named by COMPLEXITY_CONFIG, or
+// the one in the working directory if present. Paths in it are matched
+// against the paths of uploaded files.
+func loadConfig() (*config.Config, error) {
+	path := os.Getenv("COMPLEXITY_CONFIG")
+	if path == "" {
+		path = config.FileName
+		if _, err := os.Stat(path); err != nil {
+			return config.Default(), nil
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c, err := config.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
 func main() {
 	// Setup logging
 	logFile := setupLogger()
 	defer logFile.Close()
 
+	var err error
+	if cfg, err = loadConfig(); err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
 	r := setupRouter()
 
 	// Serve static files
@@ -102,8 +153,36 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
 	})
 
+	// History of the analyses recorded for a project
+	storeDir := os.Getenv("STORE_DIR")
+	if storeDir == "" {
+		storeDir = filepath.Join("data", "runs")
+	}
+	runs, err := store.NewFileStore(storeDir)
+	if err != nil {
+		log.Fatalf("Failed to open run store: %v", err)
+	}
+	r.GET("/runs", handleListRuns(runs))
+	r.GET("/runs/:id", handleGetRun(runs))
+	r.GET("/trends", handleTrends(runs))
+
 	// API endpoint for code analysis
-	r.POST("/analyze", handleAnalyze)
+	r.POST("/analyze", handleAnalyze(runs))
+
+	// Streaming variant emitting one server-sent event per analyzed file
+	r.POST("/analyze/stream", handleAnalyzeStream(runs))
+
+	// API endpoint for per-line metrics, used by the source heat map
+	r.POST("/analyze/lines", handleAnalyzeLines)
+
+	// Call graph of the uploaded files, used by the call graph view
+	r.POST("/callgraph", handleCallGraph)
+
+	// Asynchronous analysis jobs for uploads too large to analyze in one request
+	jobManager := jobs.NewManager(runtime.NumCPU(), maxQueuedJobs, cfg.Options(), cfg.Check)
+	r.POST("/jobs", handleCreateJob(jobManager))
+	r.GET("/jobs/:id", handleGetJob(jobManager))
+	r.DELETE("/jobs/:id", handleCancelJob(jobManager))
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -111,22 +190,129 @@ func main() {
 		port = "8080"
 	}
 
+	// Uploads get longer timeouts from uploadDeadlines
 	srv := &http.Server{
-		Addr:         ":" + port,
-		Handler:      r,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       5 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       120 * time.Second,
 	}
 
-	log.Printf("Server starting on port %s", port)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	// Stop on SIGINT or SIGTERM, letting requests in flight finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		errs <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-errs:
+		jobManager.Close()
 		log.Fatal(err)
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down: %v", err)
 	}
+	jobManager.Close()
 }
 
-func handleAnalyze(c *gin.Context) {
+// handleAnalyze answers with the report of the uploaded files, recording it
+// in runs when a project is given.
+func handleAnalyze(runs store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sources, ok := readSources(c, int64(cfg.Server.MaxUploadSize))
+		if !ok {
+			return
+		}
+
+		// Analyze the code
+		report, err := analyzer.AnalyzeSourcesContext(c.Request.Context(), sources, cfg.Options(), nil)
+		if err != nil {
+			return
+		}
+		report.Violations = cfg.Check(report.Functions())
+		if len(report.Functions()) == 0 && len(report.Errors()) == 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: "No functions found in uploaded files",
+			})
+			return
+		}
+
+		id, err := recordRun(c, runs, report)
+		if err != nil {
+			log.Printf("Error recording run: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error: "Failed to record run",
+			})
+			return
+		}
+		if id != "" {
+			c.Header("Location", "/runs/"+id)
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// readSources reads the uploaded "file" fields of a request of at most limit
+// bytes. On failure it writes the error response and returns false.
+func readSources(c *gin.Context, limit int64) ([]analyzer.Source, bool) {
+	// Limit upload size
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		log.Printf("Error reading upload: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Failed to get files: " + err.Error(),
+		})
+		return nil, false
+	}
+
+	files := form.File["file"]
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "No files uploaded",
+		})
+		return nil, false
+	}
+
+	sources, err := readUploads(files)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: err.Error(),
+		})
+		return nil, false
+	}
+
+	return sources, true
+}
+
+func handleAnalyzeLines(c *gin.Context) {
+	fileAnalyzer, ok := analyzeUpload(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, LinesResponse{
+		File:  fileAnalyzer.Filename(),
+		Lines: fileAnalyzer.AnalyzeLines(),
+	})
+}
+
+// analyzeUpload parses the uploaded "file" field. On failure it writes the
+// error response and returns false.
+func analyzeUpload(c *gin.Context) (*analyzer.FileAnalyzer, bool) {
 	// Limit file size
+	maxFileSize := int64(cfg.Server.MaxUploadSize)
 	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFileSize)
 
 	file, err := c.FormFile("file")
@@ -135,15 +321,15 @@ func handleAnalyze(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Error: "Failed to get file: " + err.Error(),
 		})
-		return
+		return nil, false
 	}
 
-	// Validate file extension
-	if ext := filepath.Ext(file.Filename); ext != ".go" {
+	// Validate file name
+	if filepath.Ext(file.Filename) != ".go" || !cfg.Includes(file.Filename) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: "Only .go files are supported",
+			Error: "Only .go files included by the configuration are supported",
 		})
-		return
+		return nil, false
 	}
 
 	// Validate file size
@@ -151,7 +337,7 @@ func handleAnalyze(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Error: fmt.Sprintf("File size exceeds maximum limit of %d MB", maxFileSize/(1<<20)),
 		})
-		return
+		return nil, false
 	}
 
 	// Read the file
@@ -161,7 +347,7 @@ func handleAnalyze(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error: "Failed to open file",
 		})
-		return
+		return nil, false
 	}
 	defer content.Close()
 
@@ -171,7 +357,7 @@ func handleAnalyze(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error: "Failed to read file",
 		})
-		return
+		return nil, false
 	}
 
 	// Analyze the code
@@ -181,16 +367,14 @@ func handleAnalyze(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Error: "Failed to analyze file: " + err.Error(),
 		})
-		return
+		return nil, false
 	}
-
-	results := fileAnalyzer.AnalyzeFile()
-	if len(results) == 0 {
+	if class := fileAnalyzer.Classification(); cfg.ExcludesClass(class) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: "No functions found in file",
+			Error: fmt.Sprintf("Files of class %s are excluded by the configuration", class),
 		})
-		return
+		return nil, false
 	}
 
-	c.JSON(http.StatusOK, results)
+	return fileAnalyzer, true
 }
@@ -0,0 +1,258 @@
+// Package report renders analyzer results in formats consumed by other tools.
+package report
+
+import (
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "code-complexity-viz"
+	toolURI      = "https://github.com/amanv8060/code-complexity-viz"
+
+	// srcRoot is the base the artifact locations are relative to.
+	srcRoot = "%SRCROOT%"
+)
+
+// SarifLog is the root object of a SARIF 2.1.0 document.
+type SarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SarifRun `json:"runs"`
+}
+
+// SarifRun describes a single invocation of the analyzer.
+type SarifRun struct {
+	Tool               SarifTool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]SarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Artifacts          []SarifArtifact                  `json:"artifacts,omitempty"`
+	Results            []SarifResult                    `json:"results"`
+}
+
+type SarifTool struct {
+	Driver SarifDriver `json:"driver"`
+}
+
+type SarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []SarifRule `json:"rules"`
+}
+
+// SarifRule describes one metric that can be violated.
+type SarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     SarifMessage       `json:"shortDescription"`
+	FullDescription      SarifMessage       `json:"fullDescription"`
+	DefaultConfiguration SarifConfiguration `json:"defaultConfiguration"`
+}
+
+type SarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type SarifMessage struct {
+	Text string `json:"text"`
+}
+
+type SarifArtifact struct {
+	Location SarifArtifactLocation `json:"location"`
+}
+
+// SarifResult is a single threshold violation.
+type SarifResult struct {
+	RuleID       string                  `json:"ruleId"`
+	RuleIndex    int                     `json:"ruleIndex"`
+	Level        string                  `json:"level"`
+	Message      SarifMessage            `json:"message"`
+	Locations    []SarifLocation         `json:"locations"`
+	Suppressions []SarifSuppression      `json:"suppressions,omitempty"`
+	Properties   *analyzer.MetricsResult `json:"properties,omitempty"`
+}
+
+// SarifSuppression records why a result does not fail the check: "inSource"
+// for a complexity:ignore directive, "external" for a configuration
+// exemption.
+type SarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type SarifLocation struct {
+	PhysicalLocation SarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type SarifPhysicalLocation struct {
+	ArtifactLocation SarifArtifactLocation `json:"artifactLocation"`
+	Region           SarifRegion           `json:"region"`
+}
+
+type SarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type SarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// sarifRules lists one rule per metric, in ruleIndex order.
+var sarifRules = []SarifRule{
+	newSarifRule(analyzer.MetricCyclomatic, "CyclomaticComplexity",
+		"Cyclomatic complexity is too high",
+		"The number of linearly independent paths through the function exceeds the configured maximum."),
+	newSarifRule(analyzer.MetricCognitive, "CognitiveComplexity",
+		"Cognitive complexity is too high",
+		"The function's control flow is harder to understand than the configured maximum allows."),
+	newSarifRule(analyzer.MetricNesting, "NestedDepth",
+		"Control structures are nested too deeply",
+		"The maximum nesting of control structures in the function exceeds the configured maximum."),
+	newSarifRule(analyzer.MetricParameters, "FunctionParameters",
+		"Too many parameters",
+		"The function declares more parameters than the configured maximum."),
+	newSarifRule(analyzer.MetricMaintainability, "MaintainabilityIndex",
+		"Maintainability index is too low",
+		"The maintainability index of the function is below the configured minimum."),
+}
+
+func newSarifRule(id, name, short, full string) SarifRule {
+	return SarifRule{
+		ID:                   id,
+		Name:                 name,
+		ShortDescription:     SarifMessage{Text: short},
+		FullDescription:      SarifMessage{Text: full},
+		DefaultConfiguration: SarifConfiguration{Level: "error"},
+	}
+}
+
+// SARIF converts analyzer results and their threshold violations into a
+// SARIF log with a single run. Each violation becomes a result located at
+// its function and carrying the function's metrics as properties; suppressed
+// violations are marked as such.
+//
+// File paths are made relative to root, the directory relative paths are
+// resolved against, which the run declares as %SRCROOT%. Files outside root
+// are located by absolute file URIs.
+func SARIF(root string, results []*analyzer.MetricsResult, violations []analyzer.Violation) *SarifLog {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		root = filepath.Clean(root)
+	}
+
+	ruleIndex := make(map[string]int, len(sarifRules))
+	for i, rule := range sarifRules {
+		ruleIndex[rule.ID] = i
+	}
+
+	type functionKey struct {
+		file string
+		line int
+		name string
+	}
+	functions := make(map[functionKey]*analyzer.MetricsResult, len(results))
+	files := make(map[string]bool)
+	for _, result := range results {
+		functions[functionKey{result.File, result.Line, result.QualifiedName}] = result
+		files[result.File] = true
+	}
+
+	run := SarifRun{
+		Tool: SarifTool{Driver: SarifDriver{
+			Name:           toolName,
+			InformationURI: toolURI,
+			Rules:          sarifRules,
+		}},
+		OriginalURIBaseIDs: map[string]SarifArtifactLocation{
+			srcRoot: {URI: strings.TrimSuffix(fileURI(root), "/") + "/"},
+		},
+		Results: []SarifResult{},
+	}
+
+	for _, v := range violations {
+		function := functions[functionKey{v.File, v.Line, v.QualifiedName}]
+		region := SarifRegion{StartLine: v.Line}
+		if function != nil {
+			region = SarifRegion{
+				StartLine:   function.Line,
+				StartColumn: function.Column,
+				EndLine:     function.EndLine,
+				EndColumn:   function.EndColumn,
+			}
+		}
+
+		result := SarifResult{
+			RuleID:    v.Metric,
+			RuleIndex: ruleIndex[v.Metric],
+			Level:     "error",
+			Message:   SarifMessage{Text: v.Message()},
+			Locations: []SarifLocation{{
+				PhysicalLocation: SarifPhysicalLocation{
+					ArtifactLocation: sarifLocation(root, v.File),
+					Region:           region,
+				},
+			}},
+			Properties: function,
+		}
+		if v.Suppressed {
+			kind := "inSource"
+			if v.SuppressedBy == analyzer.SuppressedByConfig {
+				kind = "external"
+			}
+			result.Suppressions = []SarifSuppression{{Kind: kind, Justification: v.Reason}}
+		}
+		run.Results = append(run.Results, result)
+		files[v.File] = true
+	}
+
+	paths := make([]string, 0, len(files))
+	for file := range files {
+		paths = append(paths, file)
+	}
+	sort.Strings(paths)
+	for _, file := range paths {
+		run.Artifacts = append(run.Artifacts, SarifArtifact{
+			Location: sarifLocation(root, file),
+		})
+	}
+
+	return &SarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []SarifRun{run},
+	}
+}
+
+// sarifLocation converts a file path into a URI reference relative to
+// root, or an absolute file URI for files outside it.
+func sarifLocation(root, path string) SarifArtifactLocation {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return SarifArtifactLocation{URI: fileURI(path)}
+	}
+	return SarifArtifactLocation{
+		URI:       (&url.URL{Path: filepath.ToSlash(rel)}).String(),
+		URIBaseID: srcRoot,
+	}
+}
+
+// fileURI converts an absolute path into a file URI.
+func fileURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // Windows drive letter
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
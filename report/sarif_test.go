@@ -0,0 +1,132 @@
+package report
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+)
+
+func TestSARIFRules(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, rule := range sarifRules {
+		if seen[rule.ID] {
+			t.Errorf("duplicate rule %s", rule.ID)
+		}
+		seen[rule.ID] = true
+	}
+	for _, metric := range []string{
+		analyzer.MetricCyclomatic,
+		analyzer.MetricCognitive,
+		analyzer.MetricNesting,
+		analyzer.MetricParameters,
+		analyzer.MetricMaintainability,
+	} {
+		if !seen[metric] {
+			t.Errorf("no rule for metric %s", metric)
+		}
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	const root = "/src/project"
+	results := []*analyzer.MetricsResult{
+		{File: "pkg/a.go", Line: 10, Column: 1, EndLine: 40, EndColumn: 2, QualifiedName: "pkg.Parse", CyclomaticComplexity: 25},
+		{File: "/src/project/pkg/b c.go", Line: 5, Column: 1, EndLine: 9, EndColumn: 2, QualifiedName: "pkg.Small"},
+		{File: "/elsewhere/x.go", Line: 3, Column: 1, EndLine: 30, EndColumn: 2, QualifiedName: "x.Run", CognitiveComplexity: 30},
+	}
+	violations := []analyzer.Violation{
+		{File: "pkg/a.go", Line: 10, QualifiedName: "pkg.Parse", Metric: analyzer.MetricCyclomatic, Value: 25, Limit: 10},
+		{File: "pkg/a.go", Line: 10, QualifiedName: "pkg.Parse", Metric: analyzer.MetricParameters, Value: 8, Limit: 5,
+			Suppressed: true, SuppressedBy: analyzer.SuppressedByDirective, Reason: "mirrors the protocol"},
+		{File: "/elsewhere/x.go", Line: 3, QualifiedName: "x.Run", Metric: analyzer.MetricCognitive, Value: 30, Limit: 15,
+			Suppressed: true, SuppressedBy: analyzer.SuppressedByConfig, Reason: "legacy"},
+	}
+
+	log := SARIF(root, results, violations)
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("version %s with %d runs, want 2.1.0 with 1 run", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if got := run.OriginalURIBaseIDs[srcRoot].URI; got != "file:///src/project/" {
+		t.Errorf("%s = %q, want file:///src/project/", srcRoot, got)
+	}
+
+	wantArtifacts := []SarifArtifactLocation{
+		{URI: "file:///elsewhere/x.go"},
+		{URI: "pkg/b%20c.go", URIBaseID: srcRoot},
+		{URI: "pkg/a.go", URIBaseID: srcRoot},
+	}
+	if len(run.Artifacts) != len(wantArtifacts) {
+		t.Fatalf("got %d artifacts, want %d", len(run.Artifacts), len(wantArtifacts))
+	}
+	for i, want := range wantArtifacts {
+		if got := run.Artifacts[i].Location; got != want {
+			t.Errorf("artifact %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	tests := []struct {
+		ruleID        string
+		location      SarifArtifactLocation
+		region        SarifRegion
+		suppression   string
+		justification string
+	}{
+		{analyzer.MetricCyclomatic, SarifArtifactLocation{URI: "pkg/a.go", URIBaseID: srcRoot}, SarifRegion{10, 1, 40, 2}, "", ""},
+		{analyzer.MetricParameters, SarifArtifactLocation{URI: "pkg/a.go", URIBaseID: srcRoot}, SarifRegion{10, 1, 40, 2}, "inSource", "mirrors the protocol"},
+		{analyzer.MetricCognitive, SarifArtifactLocation{URI: "file:///elsewhere/x.go"}, SarifRegion{3, 1, 30, 2}, "external", "legacy"},
+	}
+	if len(run.Results) != len(tests) {
+		t.Fatalf("got %d results, want %d", len(run.Results), len(tests))
+	}
+	for i, tt := range tests {
+		result := run.Results[i]
+		if result.RuleID != tt.ruleID {
+			t.Errorf("result %d: ruleId = %s, want %s", i, result.RuleID, tt.ruleID)
+		}
+		if rule := run.Tool.Driver.Rules[result.RuleIndex]; rule.ID != result.RuleID {
+			t.Errorf("result %d: ruleIndex %d points at %s, want %s", i, result.RuleIndex, rule.ID, result.RuleID)
+		}
+		location := result.Locations[0].PhysicalLocation
+		if location.ArtifactLocation != tt.location {
+			t.Errorf("result %d: location = %+v, want %+v", i, location.ArtifactLocation, tt.location)
+		}
+		if location.Region != tt.region {
+			t.Errorf("result %d: region = %+v, want %+v", i, location.Region, tt.region)
+		}
+		if result.Properties == nil {
+			t.Errorf("result %d: no properties", i)
+		}
+
+		if tt.suppression == "" {
+			if len(result.Suppressions) != 0 {
+				t.Errorf("result %d: suppressions = %+v, want none", i, result.Suppressions)
+			}
+			continue
+		}
+		want := []SarifSuppression{{Kind: tt.suppression, Justification: tt.justification}}
+		if len(result.Suppressions) != 1 || result.Suppressions[0] != want[0] {
+			t.Errorf("result %d: suppressions = %+v, want %+v", i, result.Suppressions, want)
+		}
+	}
+}
+
+func TestSARIFWithoutViolations(t *testing.T) {
+	data, err := json.Marshal(SARIF("/src", nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Runs []struct {
+			Results []json.RawMessage `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatal(err)
+	}
+	// SARIF requires the results array; an empty run must not drop it.
+	if len(log.Runs) != 1 || log.Runs[0].Results == nil {
+		t.Errorf("SARIF() = %s, want one run with an empty results array", data)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+	"github.com/aman/code-complexity-viz/store"
+	"github.com/gin-gonic/gin"
+)
+
+// recordRun saves the report as a run when the request names a project in
+// its "project" form field, labelled with the optional "revision" field. It
+// returns the ID of the run, or "" when nothing was recorded.
+func recordRun(c *gin.Context, runs store.Store, report *analyzer.Report) (string, error) {
+	project := c.PostForm("project")
+	if project == "" {
+		return "", nil
+	}
+
+	run := &store.Run{
+		Project:  project,
+		Revision: c.PostForm("revision"),
+		Results:  report.Functions(),
+	}
+	if err := runs.Save(run); err != nil {
+		return "", err
+	}
+	return run.ID, nil
+}
+
+func handleListRuns(runs store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := runs.List(c.Query("project"))
+		if err != nil {
+			log.Printf("Error listing runs: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error: "Failed to list runs",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+func handleGetRun(runs store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		run, err := runs.Get(c.Param("id"))
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error: "Run not found",
+			})
+			return
+		}
+		if err != nil {
+			log.Printf("Error reading run: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error: "Failed to read run",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, run)
+	}
+}
+
+// handleTrends answers with the series of the project named by the
+// "project" query parameter, drawn from its latest "limit" runs if given.
+func handleTrends(runs store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		project := c.Query("project")
+		if project == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: "Missing project",
+			})
+			return
+		}
+
+		limit := 0
+		if s := c.Query("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error: "Invalid limit: " + s,
+				})
+				return
+			}
+			limit = n
+		}
+
+		list, err := store.LoadRuns(runs, project, limit)
+		if err != nil {
+			log.Printf("Error loading runs: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error: "Failed to load runs",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, store.ComputeTrends(project, list))
+	}
+}
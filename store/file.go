@@ -0,0 +1,126 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileStore keeps every run as a JSON file in a directory.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore returns a store of the runs in dir, creating it if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save writes run to a new file. The file is renamed into place once
+// complete, so readers never see a partial run.
+func (s *FileStore) Save(run *Run) error {
+	if run.Timestamp.IsZero() {
+		run.Timestamp = time.Now()
+	}
+	id, err := newID(run.Timestamp)
+	if err != nil {
+		return err
+	}
+	run.ID = id
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := os.CreateTemp(s.dir, ".run-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(id))
+}
+
+// List reads the header of every run file.
+func (s *FileStore) List(project string) ([]RunInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []RunInfo{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !validID(id) {
+			continue
+		}
+
+		var header struct {
+			RunInfo
+			Results []json.RawMessage `json:"results"`
+		}
+		if err := s.read(id, &header); err != nil {
+			return nil, err
+		}
+		if project != "" && header.Project != project {
+			continue
+		}
+		info := header.RunInfo
+		info.Functions = len(header.Results)
+		runs = append(runs, info)
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Timestamp.Before(runs[j].Timestamp) })
+	return runs, nil
+}
+
+// Get reads the run file with the given ID.
+func (s *FileStore) Get(id string) (*Run, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	var run Run
+	if err := s.read(id, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (s *FileStore) read(id string, v interface{}) error {
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("run %s: %w", id, err)
+	}
+	return nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
@@ -0,0 +1,78 @@
+// Package store records analysis runs so that metrics can be compared over
+// time.
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+)
+
+// ErrNotFound is returned by Get when no run has the given ID.
+var ErrNotFound = errors.New("run not found")
+
+// Run is one recorded analysis of a project.
+type Run struct {
+	ID        string                    `json:"id"`
+	Project   string                    `json:"project"`
+	Revision  string                    `json:"revision,omitempty"` // Free-form label such as a commit hash or tag.
+	Timestamp time.Time                 `json:"timestamp"`
+	Results   []*analyzer.MetricsResult `json:"results"`
+}
+
+// RunInfo describes a run without its results.
+type RunInfo struct {
+	ID        string    `json:"id"`
+	Project   string    `json:"project"`
+	Revision  string    `json:"revision,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Functions int       `json:"functions"` // Number of results in the run.
+}
+
+// Store persists runs.
+type Store interface {
+	// Save records run, assigning its ID and, if zero, its timestamp.
+	Save(run *Run) error
+	// List returns the runs of project, oldest first. An empty project
+	// lists every run.
+	List(project string) ([]RunInfo, error)
+	// Get returns the run with the given ID or ErrNotFound.
+	Get(id string) (*Run, error)
+}
+
+// Info returns the description of the run.
+func (r *Run) Info() RunInfo {
+	return RunInfo{
+		ID:        r.ID,
+		Project:   r.Project,
+		Revision:  r.Revision,
+		Timestamp: r.Timestamp,
+		Functions: len(r.Results),
+	}
+}
+
+// newID returns an ID that sorts by the time t it was created at.
+func newID(t time.Time) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return t.UTC().Format("20060102T150405.000000000Z") + "-" + hex.EncodeToString(b), nil
+}
+
+// validID reports whether id could have been returned by newID. It keeps
+// IDs from user input out of file paths.
+func validID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f' || r == 'T' || r == 'Z' || r == '.' || r == '-') {
+			return false
+		}
+	}
+	return id[0] != '.'
+}
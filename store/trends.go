@@ -0,0 +1,155 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+)
+
+// Trends holds the evolution of a project's metrics across its runs.
+type Trends struct {
+	Project   string    `json:"project"`
+	Runs      []RunInfo `json:"runs"`      // Runs the series are drawn from, oldest first.
+	Functions []*Series `json:"functions"` // One series per function, followed across renames.
+	Files     []*Series `json:"files"`     // One series per file path.
+	Packages  []*Series `json:"packages"`  // One series per import path.
+}
+
+// Series is the evolution of one function, file or package. It has a point
+// for every run the function, file or package appears in.
+type Series struct {
+	Name    string   `json:"name"`              // Name in the latest run containing it.
+	Aliases []string `json:"aliases,omitempty"` // Earlier names of a renamed function.
+	Points  []Point  `json:"points"`
+}
+
+// Point holds the metrics of a series in one run. For files and packages the
+// metrics are totals over their functions, except maintainability which is
+// averaged.
+type Point struct {
+	Run             string    `json:"run"`
+	Revision        string    `json:"revision,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+	Functions       int       `json:"functions"`       // Number of functions, 1 in a function series.
+	Cyclomatic      int       `json:"cyclomatic"`      // Cyclomatic complexity.
+	Cognitive       int       `json:"cognitive"`       // Cognitive complexity.
+	Maintainability float64   `json:"maintainability"` // Maintainability index.
+	LinesOfCode     int       `json:"linesOfCode"`     // Lines of code, excluding closures in aggregates.
+	HalsteadEffort  float64   `json:"halsteadEffort"`  // Halstead effort.
+}
+
+// LoadRuns reads the runs of project from s, oldest first. A positive limit
+// keeps only the latest limit runs.
+func LoadRuns(s Store, project string, limit int) ([]*Run, error) {
+	infos, err := s.List(project)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(infos) > limit {
+		infos = infos[len(infos)-limit:]
+	}
+
+	runs := make([]*Run, 0, len(infos))
+	for _, info := range infos {
+		run, err := s.Get(info.ID)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// ComputeTrends builds the series of runs, which must be ordered oldest
+// first. Functions are matched from each run to the next with
+// analyzer.MatchFunctions.
+func ComputeTrends(project string, runs []*Run) *Trends {
+	trends := &Trends{
+		Project:   project,
+		Runs:      make([]RunInfo, 0, len(runs)),
+		Functions: []*Series{},
+	}
+
+	var previous []*analyzer.MetricsResult
+	current := make(map[*analyzer.MetricsResult]*Series)
+	files := make(map[string]*Series)
+	packages := make(map[string]*Series)
+	for _, run := range runs {
+		trends.Runs = append(trends.Runs, run.Info())
+
+		next := make(map[*analyzer.MetricsResult]*Series, len(run.Results))
+		for _, match := range analyzer.MatchFunctions(previous, run.Results) {
+			if match.New == nil {
+				continue
+			}
+			series := current[match.Old]
+			if series == nil {
+				series = &Series{Name: match.New.QualifiedName}
+				trends.Functions = append(trends.Functions, series)
+			} else if series.Name != match.New.QualifiedName {
+				series.Aliases = append(series.Aliases, series.Name)
+				series.Name = match.New.QualifiedName
+			}
+			series.Points = append(series.Points, functionPoint(run, match.New))
+			next[match.New] = series
+		}
+		previous, current = run.Results, next
+
+		for _, summary := range analyzer.SummarizeFiles(run.Results) {
+			addSummaryPoint(files, run, summary)
+		}
+		for _, summary := range analyzer.SummarizePackages(run.Results) {
+			addSummaryPoint(packages, run, summary)
+		}
+	}
+
+	trends.Files = sortedSeries(files)
+	trends.Packages = sortedSeries(packages)
+	sort.SliceStable(trends.Functions, func(i, j int) bool { return trends.Functions[i].Name < trends.Functions[j].Name })
+	return trends
+}
+
+func functionPoint(run *Run, result *analyzer.MetricsResult) Point {
+	return Point{
+		Run:             run.ID,
+		Revision:        run.Revision,
+		Timestamp:       run.Timestamp,
+		Functions:       1,
+		Cyclomatic:      result.CyclomaticComplexity,
+		Cognitive:       result.CognitiveComplexity,
+		Maintainability: result.MaintainabilityIndex,
+		LinesOfCode:     result.LinesOfCode,
+		HalsteadEffort:  result.HalsteadEffort,
+	}
+}
+
+// addSummaryPoint appends the point of a file or package summary to its
+// series in series, keyed by the summary's name.
+func addSummaryPoint(series map[string]*Series, run *Run, summary *analyzer.Summary) {
+	s, ok := series[summary.Name]
+	if !ok {
+		s = &Series{Name: summary.Name}
+		series[summary.Name] = s
+	}
+	s.Points = append(s.Points, Point{
+		Run:             run.ID,
+		Revision:        run.Revision,
+		Timestamp:       run.Timestamp,
+		Functions:       summary.Functions,
+		Cyclomatic:      summary.Cyclomatic.Total,
+		Cognitive:       summary.Cognitive.Total,
+		Maintainability: summary.AverageMaintainability,
+		LinesOfCode:     summary.LinesOfCode,
+		HalsteadEffort:  summary.HalsteadEffort,
+	})
+}
+
+func sortedSeries(series map[string]*Series) []*Series {
+	sorted := make([]*Series, 0, len(series))
+	for _, s := range series {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
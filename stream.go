@@ -0,0 +1,73 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+	"github.com/aman/code-complexity-viz/store"
+	"github.com/gin-gonic/gin"
+)
+
+// StreamSummary is the data of the final "summary" event of an analysis
+// stream.
+type StreamSummary struct {
+	Summary    *analyzer.Summary          `json:"summary"`
+	Packages   []*analyzer.Summary        `json:"packages"`
+	Coupling   []*analyzer.PackageMetrics `json:"coupling"`
+	Cohesion   []*analyzer.TypeCohesion   `json:"cohesion"`
+	Errors     int                        `json:"errors"`
+	Violations []analyzer.Violation       `json:"violations,omitempty"`
+	RunID      string                     `json:"runId,omitempty"` // Set when the analysis was recorded.
+}
+
+// handleAnalyzeStream analyzes the uploaded files like handleAnalyze but
+// responds with server-sent events: a "file" event carrying each file's
+// report as soon as it is analyzed, then a "summary" event. Like
+// handleAnalyze it records the report in runs when a project is given.
+func handleAnalyzeStream(runs store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sources, ok := readSources(c, int64(cfg.Server.MaxJobUploadSize))
+		if !ok {
+			return
+		}
+
+		// The stream may outlive the server's write timeout
+		if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+			log.Printf("Error clearing write deadline: %v", err)
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		// Stop analyzing once the client goes away
+		report, err := analyzer.AnalyzeSourcesContext(c.Request.Context(), sources, cfg.Options(), func(file *analyzer.FileReport) {
+			c.SSEvent("file", file)
+			c.Writer.Flush()
+		})
+		if err != nil {
+			return
+		}
+
+		summary := StreamSummary{
+			Summary:    report.Summary,
+			Coupling:   report.Coupling(),
+			Cohesion:   report.Cohesion(),
+			Errors:     len(report.Errors()),
+			Violations: cfg.Check(report.Functions()),
+		}
+		for _, pkg := range report.Packages {
+			summary.Packages = append(summary.Packages, pkg.Summary)
+		}
+		if summary.RunID, err = recordRun(c, runs, report); err != nil {
+			// The files have been streamed already; report the failure in
+			// the log only
+			log.Printf("Error recording run: %v", err)
+		}
+		c.SSEvent("summary", summary)
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+	"github.com/gin-gonic/gin"
+)
+
+const maxArchiveFiles = 10000
+
+// uploadTimeout bounds reading an upload and writing its response, for
+// which the server's timeouts are too short.
+const uploadTimeout = 5 * time.Minute
+
+// uploadDeadlines extends the read and write deadlines of multipart
+// uploads to uploadTimeout. It must come before middleware wrapping the
+// response writer, like gzip, which hides the connection's deadlines.
+func uploadDeadlines(c *gin.Context) {
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		rc := http.NewResponseController(c.Writer)
+		deadline := time.Now().Add(uploadTimeout)
+		if err := rc.SetReadDeadline(deadline); err != nil {
+			log.Printf("Error extending read deadline: %v", err)
+		}
+		if err := rc.SetWriteDeadline(deadline); err != nil {
+			log.Printf("Error extending write deadline: %v", err)
+		}
+	}
+	c.Next()
+}
+
+// sourceCollector accumulates the sources of an upload while enforcing the
+// limits on extracted size and file count. Only files the configuration
+// includes are kept, and every path must be unique.
+type sourceCollector struct {
+	sources []analyzer.Source
+	paths   map[string]bool
+	size    int64
+}
+
+// readUploads reads every uploaded file: plain .go files as they are, and
+// the .go and go.mod files contained in .zip, .tar.gz and .tgz archives.
+func readUploads(files []*multipart.FileHeader) ([]analyzer.Source, error) {
+	collector := &sourceCollector{paths: make(map[string]bool)}
+	for _, file := range files {
+		if err := collector.addUpload(file); err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Filename, err)
+		}
+	}
+	return collector.sources, nil
+}
+
+func (sc *sourceCollector) addUpload(file *multipart.FileHeader) error {
+	name := strings.ToLower(file.Filename)
+	archive := strings.HasSuffix(name, ".zip") || strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+	if !archive && (!strings.HasSuffix(name, ".go") || !cfg.Includes(path.Base(file.Filename))) {
+		return errors.New("only .go files included by the configuration and .zip, .tar.gz or .tgz archives are supported")
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return errors.New("failed to open file")
+	}
+	defer f.Close()
+
+	switch {
+	case strings.HasSuffix(name, ".go"):
+		// Multipart file names carry no directory, so files of the same name
+		// from different directories collide and are rejected by add
+		return sc.add(file.Filename, f)
+	case strings.HasSuffix(name, ".zip"):
+		return sc.addZip(f, file.Size)
+	default:
+		return sc.addTarGz(f)
+	}
+}
+
+func (sc *sourceCollector) addZip(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %w", err)
+	}
+	for _, entry := range zr.File {
+		if entry.FileInfo().IsDir() || !wantArchiveEntry(entry.Name) {
+			continue
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("invalid zip archive: %w", err)
+		}
+		err = sc.add(entry.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sc *sourceCollector) addTarGz(r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("invalid gzip stream: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || !wantArchiveEntry(header.Name) {
+			continue
+		}
+		if err := sc.add(header.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// add reads one source, rejecting it once the upload exceeds its limits.
+func (sc *sourceCollector) add(name string, r io.Reader) error {
+	if len(sc.sources) >= maxArchiveFiles {
+		return fmt.Errorf("more than %d source files", maxArchiveFiles)
+	}
+	name = cleanArchivePath(name)
+	if sc.paths[name] {
+		return fmt.Errorf("duplicate file %s; upload files of the same name in an archive", name)
+	}
+	sc.paths[name] = true
+
+	maxSize := int64(cfg.Server.MaxExtractedSize)
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(r, maxSize-sc.size+1))
+	if err != nil {
+		return fmt.Errorf("failed to read %s", name)
+	}
+	sc.size += n
+	if sc.size > maxSize {
+		return fmt.Errorf("extracted sources exceed %d MB", maxSize>>20)
+	}
+
+	sc.sources = append(sc.sources, analyzer.Source{Path: name, Content: buf.Bytes()})
+	return nil
+}
+
+// wantArchiveEntry reports whether an archive entry is a source to analyze
+// or a go.mod file naming its packages.
+func wantArchiveEntry(name string) bool {
+	name = cleanArchivePath(name)
+	if name == "" || strings.HasPrefix(name, "__MACOSX/") {
+		return false
+	}
+	return path.Ext(name) == ".go" && cfg.Includes(name) || path.Base(name) == "go.mod"
+}
+
+// cleanArchivePath normalizes an archive entry name to a relative slash path
+// that cannot escape the archive root.
+func cleanArchivePath(name string) string {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+	return strings.TrimPrefix(name, "/")
+}
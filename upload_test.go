@@ -0,0 +1,232 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+	"github.com/aman/code-complexity-viz/config"
+)
+
+// withConfig replaces the server configuration for the duration of a test.
+func withConfig(t *testing.T, c *config.Config) {
+	t.Helper()
+	saved := cfg
+	cfg = c
+	t.Cleanup(func() { cfg = saved })
+}
+
+type archiveEntry struct {
+	name    string
+	content string
+}
+
+func zipArchive(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		w, err := zw.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarGzArchive(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCleanArchivePath(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"pkg/a.go", "pkg/a.go"},
+		{"./pkg/a.go", "pkg/a.go"},
+		{"pkg//sub/../a.go", "pkg/a.go"},
+		{"../a.go", "a.go"},
+		{"../../etc/passwd", "etc/passwd"},
+		{"pkg/../../a.go", "a.go"},
+		{"/etc/passwd", "etc/passwd"},
+		{"..\\..\\windows\\a.go", "windows/a.go"},
+		{"C:\\src\\a.go", "C:/src/a.go"},
+		{"", ""},
+		{"..", ""},
+		{"/", ""},
+	}
+	for _, tt := range tests {
+		if got := cleanArchivePath(tt.name); got != tt.want {
+			t.Errorf("cleanArchivePath(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWantArchiveEntry(t *testing.T) {
+	withConfig(t, config.Default())
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"pkg/a.go", true},
+		{"../pkg/a.go", true},
+		{"go.mod", true},
+		{"module/go.mod", true},
+		{"pkg/README.md", false},
+		{"go.sum", false},
+		{"__MACOSX/pkg/._a.go", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := wantArchiveEntry(tt.name); got != tt.want {
+			t.Errorf("wantArchiveEntry(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSourceCollector(t *testing.T) {
+	tests := []struct {
+		name      string
+		entries   []archiveEntry
+		maxSize   config.Size
+		wantPaths []string
+		wantErr   string
+	}{
+		{
+			name: "sources and go.mod",
+			entries: []archiveEntry{
+				{"go.mod", "module example\n"},
+				{"pkg/a.go", "package pkg\n"},
+				{"pkg/README.md", "# pkg\n"},
+				{"__MACOSX/pkg/._a.go", "\x00"},
+			},
+			wantPaths: []string{"go.mod", "pkg/a.go"},
+		},
+		{
+			name: "escaping paths are cleaned",
+			entries: []archiveEntry{
+				{"../../outside.go", "package outside\n"},
+				{"/abs/a.go", "package abs\n"},
+			},
+			wantPaths: []string{"outside.go", "abs/a.go"},
+		},
+		{
+			name: "duplicate names",
+			entries: []archiveEntry{
+				{"pkg/a.go", "package pkg\n"},
+				{"pkg/sub/../a.go", "package pkg\n"},
+			},
+			wantErr: "duplicate file pkg/a.go",
+		},
+		{
+			name: "duplicates after cleaning an escaping path",
+			entries: []archiveEntry{
+				{"a.go", "package a\n"},
+				{"../a.go", "package a\n"},
+			},
+			wantErr: "duplicate file a.go",
+		},
+		{
+			name: "within the extracted size limit",
+			entries: []archiveEntry{
+				{"a.go", strings.Repeat("a", 512)},
+				{"b.go", strings.Repeat("b", 512)},
+			},
+			maxSize:   1024,
+			wantPaths: []string{"a.go", "b.go"},
+		},
+		{
+			name: "over the extracted size limit",
+			entries: []archiveEntry{
+				{"a.go", strings.Repeat("a", 512)},
+				{"b.go", strings.Repeat("b", 513)},
+			},
+			maxSize: 1024,
+			wantErr: "extracted sources exceed",
+		},
+	}
+	archives := []struct {
+		name string
+		add  func(t *testing.T, sc *sourceCollector, entries []archiveEntry) error
+	}{
+		{"zip", func(t *testing.T, sc *sourceCollector, entries []archiveEntry) error {
+			data := zipArchive(t, entries)
+			return sc.addZip(bytes.NewReader(data), int64(len(data)))
+		}},
+		{"tar.gz", func(t *testing.T, sc *sourceCollector, entries []archiveEntry) error {
+			return sc.addTarGz(bytes.NewReader(tarGzArchive(t, entries)))
+		}},
+	}
+
+	for _, archive := range archives {
+		for _, tt := range tests {
+			t.Run(archive.name+"/"+tt.name, func(t *testing.T) {
+				c := config.Default()
+				if tt.maxSize > 0 {
+					c.Server.MaxExtractedSize = tt.maxSize
+				}
+				withConfig(t, c)
+
+				sc := &sourceCollector{paths: make(map[string]bool)}
+				err := archive.add(t, sc, tt.entries)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				var paths []string
+				for _, source := range sc.sources {
+					paths = append(paths, source.Path)
+				}
+				if strings.Join(paths, " ") != strings.Join(tt.wantPaths, " ") {
+					t.Errorf("paths = %q, want %q", paths, tt.wantPaths)
+				}
+			})
+		}
+	}
+}
+
+func TestSourceCollectorFileLimit(t *testing.T) {
+	withConfig(t, config.Default())
+	sc := &sourceCollector{
+		sources: make([]analyzer.Source, maxArchiveFiles),
+		paths:   make(map[string]bool),
+	}
+	if err := sc.add("a.go", strings.NewReader("package a\n")); err == nil {
+		t.Errorf("add() beyond %d files succeeded", maxArchiveFiles)
+	}
+}
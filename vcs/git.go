@@ -0,0 +1,129 @@
+// Package vcs reads the sources of past revisions from a git repository by
+// running the git binary.
+package vcs
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/aman/code-complexity-viz/analyzer"
+)
+
+// Change states reported in Change.Status.
+const (
+	Added    = "A"
+	Deleted  = "D"
+	Modified = "M"
+)
+
+// Change is a file that differs between two revisions. Renames are reported
+// as a deletion and an addition.
+type Change struct {
+	Path   string // Slash-separated path relative to the repository root.
+	Status string // Added, Deleted or Modified.
+}
+
+// Repo is a git repository on disk.
+type Repo struct {
+	root string
+}
+
+// Open returns the repository containing dir.
+func Open(dir string) (*Repo, error) {
+	out, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	return &Repo{root: strings.TrimSpace(string(out))}, nil
+}
+
+// Root returns the top-level directory of the repository.
+func (r *Repo) Root() string {
+	return r.root
+}
+
+// ResolveRevision returns the commit hash rev names.
+func (r *Repo) ResolveRevision(rev string) (string, error) {
+	out, err := git(r.root, "rev-parse", "--verify", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("revision %q: %w", rev, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// MergeBase returns the best common ancestor of the commits a and b.
+func (r *Repo) MergeBase(a, b string) (string, error) {
+	out, err := git(r.root, "merge-base", a, b)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ChangedGoFiles returns the .go files that differ between base and head.
+func (r *Repo) ChangedGoFiles(base, head string) ([]Change, error) {
+	out, err := git(r.root, "diff", "--name-status", "--no-renames", "-z", base, head, "--", "*.go")
+	if err != nil {
+		return nil, err
+	}
+
+	// -z separates the status and the path of every entry with NUL
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	var changes []Change
+	for i := 0; i+1 < len(fields); i += 2 {
+		status := fields[i][:1]
+		if status != Added && status != Deleted {
+			status = Modified
+		}
+		changes = append(changes, Change{Path: fields[i+1], Status: status})
+	}
+	return changes, nil
+}
+
+// ReadFile returns the content of the file at path in revision rev.
+func (r *Repo) ReadFile(rev, path string) ([]byte, error) {
+	return git(r.root, "cat-file", "blob", rev+":"+path)
+}
+
+// Sources returns the sources of the given paths at revision rev, together
+// with every go.mod file of the revision so that packages are named by
+// their import path.
+func (r *Repo) Sources(rev string, paths []string) ([]analyzer.Source, error) {
+	out, err := git(r.root, "ls-tree", "-r", "-z", "--name-only", rev)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(string(out), "\x00") {
+		if path.Base(name) == "go.mod" {
+			paths = append(paths, name)
+		}
+	}
+
+	sources := make([]analyzer.Source, 0, len(paths))
+	for _, p := range paths {
+		content, err := r.ReadFile(rev, p)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, analyzer.Source{Path: p, Content: content})
+	}
+	return sources, nil
+}
+
+// git runs a git command in dir and returns its standard output.
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"go/token"
 	"syscall/js"
 
 	"github.com/aman/code-complexity-viz/analyzer"
@@ -11,6 +12,8 @@ import (
 func main() {
 	c := make(chan struct{}, 0)
 	js.Global().Set("analyzeGoCode", js.FuncOf(analyzeGoCode))
+	js.Global().Set("analyzeGoLines", js.FuncOf(analyzeGoLines))
+	js.Global().Set("callGraphGoCode", js.FuncOf(callGraphGoCode))
 	<-c
 }
 
@@ -22,13 +25,107 @@ func analyzeGoCode(this js.Value, args []js.Value) (result interface{}) {
 		}
 	}()
 
+	fileAnalyzer, errMsg := parseCode(args)
+	if errMsg != "" {
+		return wrap(errMsg, nil)
+	}
+
+	results := fileAnalyzer.AnalyzeFile()
+	if len(results) == 0 {
+		return wrap("No functions found", nil)
+	}
+
+	// Convert results to JSON
+	jsonData, err := json.Marshal(results)
+	if err != nil {
+		return wrap(err.Error(), nil)
+	}
+
+	return wrap("", string(jsonData))
+}
+
+func analyzeGoLines(this js.Value, args []js.Value) (result interface{}) {
+	// Recover from panics
+	defer func() {
+		if r := recover(); r != nil {
+			result = wrap("Internal error: "+fmt.Sprint(r), nil)
+		}
+	}()
+
+	fileAnalyzer, errMsg := parseCode(args)
+	if errMsg != "" {
+		return wrap(errMsg, nil)
+	}
+
+	jsonData, err := json.Marshal(fileAnalyzer.AnalyzeLines())
+	if err != nil {
+		return wrap(err.Error(), nil)
+	}
+
+	return wrap("", string(jsonData))
+}
+
+// callGraphGoCode builds the call graph of an array of {name, code} files of
+// one package. Imports cannot be type-checked in the browser, so only the
+// calls between the given files are resolved.
+func callGraphGoCode(this js.Value, args []js.Value) (result interface{}) {
+	// Recover from panics
+	defer func() {
+		if r := recover(); r != nil {
+			result = wrap("Internal error: "+fmt.Sprint(r), nil)
+		}
+	}()
+
+	if len(args) < 1 || args[0].Type() != js.TypeObject {
+		return wrap("Error: Expected an array of files", nil)
+	}
+
+	fset := token.NewFileSet()
+	var analyzers []*analyzer.FileAnalyzer
+	size := 0
+	for i := 0; i < args[0].Length(); i++ {
+		file := args[0].Index(i)
+		code := file.Get("code").String()
+		size += len(code)
+		if size > 5000000 { // 5MB limit
+			return wrap("Error: Code size exceeds limit", nil)
+		}
+		fileAnalyzer, err := analyzer.ParseFile(fset, file.Get("name").String(), []byte(code))
+		if err != nil {
+			return wrap(err.Error(), nil)
+		}
+		analyzers = append(analyzers, fileAnalyzer)
+	}
+
+	// Without a fallback importer, imports resolve to empty packages
+	analyzer.NumberInits(analyzers)
+	analyzer.LoadPackages(fset, analyzers, nil)
+	var functions []*analyzer.MetricsResult
+	for _, fileAnalyzer := range analyzers {
+		functions = append(functions, fileAnalyzer.AnalyzeFile()...)
+	}
+	if len(functions) == 0 {
+		return wrap("No functions found", nil)
+	}
+
+	jsonData, err := json.Marshal(analyzer.LinkCalls(functions))
+	if err != nil {
+		return wrap(err.Error(), nil)
+	}
+
+	return wrap("", string(jsonData))
+}
+
+// parseCode validates the code and optional file name passed from
+// JavaScript and parses it. It returns an error message on failure.
+func parseCode(args []js.Value) (*analyzer.FileAnalyzer, string) {
 	if len(args) < 1 {
-		return wrap("Error: No code provided", nil)
+		return nil, "Error: No code provided"
 	}
 
 	// Validate input type
 	if args[0].Type() != js.TypeString {
-		return wrap("Error: Input must be a string", nil)
+		return nil, "Error: Input must be a string"
 	}
 
 	// Get code from JavaScript
@@ -36,30 +133,25 @@ func analyzeGoCode(this js.Value, args []js.Value) (result interface{}) {
 
 	// Validate code length
 	if len(code) == 0 {
-		return wrap("Error: Empty code provided", nil)
+		return nil, "Error: Empty code provided"
 	}
 	if len(code) > 5000000 { // 5MB limit
-		return wrap("Error: Code size exceeds limit", nil)
+		return nil, "Error: Code size exceeds limit"
 	}
 
-	// Analyze the code
-	fileAnalyzer, err := analyzer.NewFileAnalyzer("temp.go", []byte(code))
-	if err != nil {
-		return wrap(err.Error(), nil)
+	// Optional file name reported in the source positions
+	filename := "temp.go"
+	if len(args) > 1 && args[1].Type() == js.TypeString && args[1].String() != "" {
+		filename = args[1].String()
 	}
 
-	results := fileAnalyzer.AnalyzeFile()
-	if len(results) == 0 {
-		return wrap("No functions found", nil)
-	}
-
-	// Convert results to JSON
-	jsonData, err := json.Marshal(results)
+	// Analyze the code
+	fileAnalyzer, err := analyzer.NewFileAnalyzer(filename, []byte(code))
 	if err != nil {
-		return wrap(err.Error(), nil)
+		return nil, err.Error()
 	}
 
-	return wrap("", string(jsonData))
+	return fileAnalyzer, ""
 }
 
 func wrap(err string, data interface{}) js.Value {